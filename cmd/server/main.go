@@ -46,7 +46,9 @@ func main() {
 
 	// Setup API routes
 	apiGroup := router.Group("/api")
-	api.SetupRoutes(apiGroup, memoryStorage, l)
+	if err := api.SetupRoutes(apiGroup, memoryStorage, l, cfg); err != nil {
+		log.Fatalf("Failed to setup API routes: %v", err)
+	}
 
 	// Serve static files
 	router.NoRoute(gin.WrapH(http.FileServer(http.Dir("./web"))))
@@ -0,0 +1,387 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrlesur/ontology-server/internal/logger"
+	"github.com/chrlesur/ontology-server/internal/models"
+	"github.com/chrlesur/ontology-server/internal/search"
+)
+
+// Limites appliquées à l'évaluation des recherches enregistrées
+const (
+	// maxInboxSize borne le nombre de correspondances conservées par recherche
+	maxInboxSize = 1000
+	// evaluationTimeout borne la durée d'évaluation d'une recherche sur une ontologie
+	evaluationTimeout = 30 * time.Second
+	// webhookTimeout borne la durée d'une livraison au webhook
+	webhookTimeout = 10 * time.Second
+)
+
+// ErrSavedSearchNotFound signale une recherche enregistrée inconnue
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// Hit est une correspondance trouvée dans une ontologie nouvellement chargée
+type Hit struct {
+	OntologyID   string `json:"ontology_id"`
+	OntologyName string `json:"ontology_name"`
+	// OntologyHash identifie le contenu de l'ontologie, lorsqu'il est connu, pour reconnaître un rechargement
+	OntologyHash string    `json:"ontology_hash,omitempty"`
+	ElementName  string    `json:"element_name"`
+	ElementType  string    `json:"element_type"`
+	Description  string    `json:"description"`
+	Relevance    float64   `json:"relevance"`
+	DetectedAt   time.Time `json:"detected_at"`
+}
+
+// key identifie une correspondance indépendamment du chargement : le même élément d'une ontologie
+// rechargée depuis les mêmes fichiers n'est signalé qu'une fois
+func (h Hit) key() string {
+	ontology := h.OntologyHash
+	if ontology == "" {
+		ontology = h.OntologyName
+	}
+	return ontology + "\x00" + h.ElementName
+}
+
+// SavedSearch est une recherche enregistrée, réévaluée à chaque chargement d'ontologie
+type SavedSearch struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Query         string    `json:"query"`
+	ElementTypes  []string  `json:"element_types,omitempty"`
+	RelationTypes []string  `json:"relation_types,omitempty"`
+	Mode          string    `json:"mode,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	// Inbox contient les correspondances détectées, de la plus ancienne à la plus récente
+	Inbox []Hit `json:"inbox"`
+}
+
+// options convertit la recherche enregistrée en critères de recherche limités à une ontologie
+func (s *SavedSearch) options(ontologyID string) search.SearchOptions {
+	return search.SearchOptions{
+		Query:         s.Query,
+		OntologyIDs:   []string{ontologyID},
+		ElementTypes:  s.ElementTypes,
+		RelationTypes: s.RelationTypes,
+		Mode:          s.Mode,
+		// Les évaluations ne doivent pas remplir le cache partagé d'entrées propres à une ontologie
+		NoCache: true,
+	}
+}
+
+// webhookPayload est le corps envoyé au webhook pour chaque recherche ayant de nouvelles correspondances
+type webhookPayload struct {
+	SavedSearchID   string `json:"saved_search_id"`
+	SavedSearchName string `json:"saved_search_name"`
+	Query           string `json:"query"`
+	OntologyID      string `json:"ontology_id"`
+	Hits            []Hit  `json:"hits"`
+}
+
+// Manager conserve les recherches enregistrées, les persiste dans un fichier JSON et les évalue
+// sur chaque ontologie chargée
+type Manager struct {
+	mutex    sync.RWMutex
+	searches map[string]*SavedSearch
+	engine   *search.SearchEngine
+	logger   *logger.Logger
+	// file est le fichier de persistance ; vide, les recherches ne sont conservées qu'en mémoire
+	file       string
+	webhookURL string
+	client     *http.Client
+	// evaluating sérialise les évaluations, exécutées en arrière-plan, et evaluations les suit
+	evaluating  sync.Mutex
+	evaluations sync.WaitGroup
+	// deliveries suit les livraisons au webhook en cours
+	deliveries sync.WaitGroup
+}
+
+// NewManager crée le gestionnaire et recharge les recherches enregistrées dans file, s'il existe
+func NewManager(engine *search.SearchEngine, logger *logger.Logger, file, webhookURL string) (*Manager, error) {
+	m := &Manager{
+		searches:   make(map[string]*SavedSearch),
+		engine:     engine,
+		logger:     logger,
+		file:       file,
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: webhookTimeout},
+	}
+	if file == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved searches: %w", err)
+	}
+	var searches []*SavedSearch
+	if err := json.Unmarshal(data, &searches); err != nil {
+		return nil, fmt.Errorf("failed to parse saved searches: %w", err)
+	}
+	for _, s := range searches {
+		m.searches[s.ID] = s
+	}
+	logger.Info(fmt.Sprintf("Loaded %d saved searches from %s", len(searches), file))
+	return m, nil
+}
+
+// Create enregistre une nouvelle recherche
+func (m *Manager) Create(s SavedSearch) (*SavedSearch, error) {
+	s.Query = strings.TrimSpace(s.Query)
+	if s.Query == "" {
+		return nil, fmt.Errorf("a saved search needs a query")
+	}
+	s.ID = fmt.Sprintf("search_%d", time.Now().UnixNano())
+	s.CreatedAt = time.Now()
+	s.Inbox = []Hit{}
+	if s.Name == "" {
+		s.Name = s.Query
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.searches[s.ID] = &s
+	if err := m.persist(); err != nil {
+		delete(m.searches, s.ID)
+		return nil, err
+	}
+	m.logger.Info(fmt.Sprintf("Saved search %s created for query: %s", s.ID, s.Query))
+	return m.copy(&s), nil
+}
+
+// List retourne les recherches enregistrées, sans leur boîte de réception, par date de création
+func (m *Manager) List() []SavedSearch {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	searches := make([]SavedSearch, 0, len(m.searches))
+	for _, s := range m.searches {
+		summary := *s
+		summary.Inbox = nil
+		searches = append(searches, summary)
+	}
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].CreatedAt.Before(searches[j].CreatedAt)
+	})
+	return searches
+}
+
+// Get retourne une recherche enregistrée avec sa boîte de réception
+func (m *Manager) Get(id string) (*SavedSearch, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	s, exists := m.searches[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrSavedSearchNotFound, id)
+	}
+	return m.copy(s), nil
+}
+
+// Delete supprime une recherche enregistrée
+func (m *Manager) Delete(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.searches[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrSavedSearchNotFound, id)
+	}
+	delete(m.searches, id)
+	return m.persist()
+}
+
+// ClearInbox vide la boîte de réception d'une recherche enregistrée
+func (m *Manager) ClearInbox(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s, exists := m.searches[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSavedSearchNotFound, id)
+	}
+	s.Inbox = []Hit{}
+	return m.persist()
+}
+
+// Evaluate exécute en arrière-plan chaque recherche enregistrée sur une ontologie nouvellement chargée,
+// afin de ne pas retarder la réponse au chargement. Les évaluations s'exécutent l'une après l'autre.
+func (m *Manager) Evaluate(ontology *models.Ontology) {
+	m.evaluations.Add(1)
+	go func() {
+		defer m.evaluations.Done()
+		m.evaluating.Lock()
+		defer m.evaluating.Unlock()
+		m.evaluate(ontology)
+	}()
+}
+
+// evaluate ajoute aux boîtes de réception les correspondances qui n'y figurent pas encore
+// et les transmet au webhook configuré
+func (m *Manager) evaluate(ontology *models.Ontology) {
+	m.mutex.RLock()
+	searches := make([]*SavedSearch, 0, len(m.searches))
+	for _, s := range m.searches {
+		searches = append(searches, s)
+	}
+	m.mutex.RUnlock()
+
+	var payloads []webhookPayload
+	for _, s := range searches {
+		ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+		response, err := m.engine.SearchWithOptions(ctx, s.options(ontology.ID))
+		cancel()
+		if err != nil {
+			m.logger.Error(fmt.Sprintf("Failed to evaluate saved search %s on ontology %s: %v", s.ID, ontology.ID, err))
+			continue
+		}
+		if len(response.Results) == 0 {
+			continue
+		}
+
+		now := time.Now()
+		m.mutex.Lock()
+		if _, exists := m.searches[s.ID]; !exists {
+			m.mutex.Unlock()
+			continue
+		}
+		known := make(map[string]bool, len(s.Inbox))
+		for _, hit := range s.Inbox {
+			known[hit.key()] = true
+		}
+		var hits []Hit
+		for _, result := range response.Results {
+			hit := Hit{
+				OntologyID:   ontology.ID,
+				OntologyName: ontology.Name,
+				OntologyHash: ontology.SHA256,
+				ElementName:  result.ElementName,
+				ElementType:  result.ElementType,
+				Description:  result.Description,
+				Relevance:    result.Relevance,
+				DetectedAt:   now,
+			}
+			if known[hit.key()] {
+				continue
+			}
+			known[hit.key()] = true
+			hits = append(hits, hit)
+		}
+		s.Inbox = append(s.Inbox, hits...)
+		if len(s.Inbox) > maxInboxSize {
+			s.Inbox = s.Inbox[len(s.Inbox)-maxInboxSize:]
+		}
+		m.mutex.Unlock()
+		if len(hits) == 0 {
+			continue
+		}
+
+		m.logger.Info(fmt.Sprintf("Saved search %s matched %d elements in ontology %s", s.ID, len(hits), ontology.ID))
+		payloads = append(payloads, webhookPayload{
+			SavedSearchID:   s.ID,
+			SavedSearchName: s.Name,
+			Query:           s.Query,
+			OntologyID:      ontology.ID,
+			Hits:            hits,
+		})
+	}
+	if len(payloads) == 0 {
+		return
+	}
+
+	m.mutex.Lock()
+	if err := m.persist(); err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to persist saved searches: %v", err))
+	}
+	m.mutex.Unlock()
+
+	if m.webhookURL != "" {
+		m.deliveries.Add(1)
+		go func() {
+			defer m.deliveries.Done()
+			for _, payload := range payloads {
+				m.deliver(payload)
+			}
+		}()
+	}
+}
+
+// Wait attend la fin des évaluations et des livraisons au webhook en cours
+func (m *Manager) Wait() {
+	m.evaluations.Wait()
+	m.deliveries.Wait()
+}
+
+// deliver envoie les nouvelles correspondances d'une recherche au webhook
+func (m *Manager) deliver(payload webhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to encode webhook payload: %v", err))
+		return
+	}
+
+	resp, err := m.client.Post(m.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to deliver saved search %s to webhook: %v", payload.SavedSearchID, err))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		m.logger.Warning(fmt.Sprintf("Webhook answered %d for saved search %s", resp.StatusCode, payload.SavedSearchID))
+		return
+	}
+	m.logger.Info(fmt.Sprintf("Delivered %d hits of saved search %s to webhook", len(payload.Hits), payload.SavedSearchID))
+}
+
+// persist écrit les recherches enregistrées dans le fichier de persistance. L'appelant détient le verrou.
+func (m *Manager) persist() error {
+	if m.file == "" {
+		return nil
+	}
+
+	searches := make([]*SavedSearch, 0, len(m.searches))
+	for _, s := range m.searches {
+		searches = append(searches, s)
+	}
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].CreatedAt.Before(searches[j].CreatedAt)
+	})
+	data, err := json.MarshalIndent(searches, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode saved searches: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.file), 0755); err != nil {
+		return fmt.Errorf("failed to create saved searches directory: %w", err)
+	}
+	// Écrire dans un fichier temporaire puis le renommer pour ne jamais laisser un fichier tronqué
+	tmp := m.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write saved searches: %w", err)
+	}
+	if err := os.Rename(tmp, m.file); err != nil {
+		return fmt.Errorf("failed to write saved searches: %w", err)
+	}
+	return nil
+}
+
+// copy retourne une copie d'une recherche enregistrée, indépendante des modifications ultérieures
+func (m *Manager) copy(s *SavedSearch) *SavedSearch {
+	c := *s
+	c.Inbox = append([]Hit{}, s.Inbox...)
+	return &c
+}
@@ -0,0 +1,109 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/chrlesur/ontology-server/internal/logger"
+	"github.com/chrlesur/ontology-server/internal/models"
+	"github.com/chrlesur/ontology-server/internal/search"
+	"github.com/chrlesur/ontology-server/internal/storage"
+)
+
+func TestSavedSearchEvaluation(t *testing.T) {
+	l, err := logger.NewLogger(logger.ERROR, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	ms := storage.NewMemoryStorage()
+	engine := search.NewSearchEngine(ms, l)
+
+	var mutex sync.Mutex
+	var delivered []webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		mutex.Lock()
+		delivered = append(delivered, payload)
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "saved_searches.json")
+	manager, err := NewManager(engine, l, file, server.URL)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if _, err := manager.Create(SavedSearch{Query: "  "}); err == nil {
+		t.Error("Expected error for an empty query, got nil")
+	}
+	saved, err := manager.Create(SavedSearch{Name: "Juridictions", Query: "conseil"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	ontology := &models.Ontology{
+		ID:   "onto1",
+		Name: "Droit public",
+		Elements: []*models.OntologyElement{
+			{Name: "Conseil_Etat", Type: "Organisation"},
+			{Name: "Sport", Type: "Concept"},
+		},
+	}
+	ms.AddOntology(ontology)
+	manager.Evaluate(ontology)
+	manager.Wait()
+
+	inbox, err := manager.Get(saved.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(inbox.Inbox) != 1 || inbox.Inbox[0].ElementName != "Conseil_Etat" || inbox.Inbox[0].OntologyID != "onto1" {
+		t.Fatalf("Expected Conseil_Etat in the inbox, got %+v", inbox.Inbox)
+	}
+	if len(delivered) != 1 || delivered[0].SavedSearchID != saved.ID || len(delivered[0].Hits) != 1 {
+		t.Errorf("Expected one webhook delivery, got %+v", delivered)
+	}
+
+	// Recharger la même ontologie ne signale pas à nouveau les mêmes éléments
+	reloadedOntology := *ontology
+	reloadedOntology.ID = "onto2"
+	ms.AddOntology(&reloadedOntology)
+	manager.Evaluate(&reloadedOntology)
+	manager.Wait()
+	if inbox, _ = manager.Get(saved.ID); len(inbox.Inbox) != 1 {
+		t.Errorf("Expected the reloaded element not to be added again, got %+v", inbox.Inbox)
+	}
+	mutex.Lock()
+	if len(delivered) != 1 {
+		t.Errorf("Expected no webhook delivery for known hits, got %+v", delivered)
+	}
+	mutex.Unlock()
+	if stats := engine.Cache.Stats(); stats.Entries != 0 {
+		t.Errorf("Expected evaluations to bypass the search cache, got %+v", stats)
+	}
+
+	// Les recherches et leurs boîtes de réception survivent à un redémarrage
+	reloaded, err := NewManager(engine, l, file, "")
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	restored, err := reloaded.Get(saved.ID)
+	if err != nil || len(restored.Inbox) != 1 {
+		t.Fatalf("Expected the saved search to be restored, got %+v (%v)", restored, err)
+	}
+
+	if err := reloaded.ClearInbox(saved.ID); err != nil {
+		t.Fatalf("ClearInbox failed: %v", err)
+	}
+	if restored, _ = reloaded.Get(saved.ID); len(restored.Inbox) != 0 {
+		t.Errorf("Expected an empty inbox, got %+v", restored.Inbox)
+	}
+	if err := reloaded.Delete("unknown"); err == nil {
+		t.Error("Expected error when deleting an unknown saved search, got nil")
+	}
+}
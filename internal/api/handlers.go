@@ -1,26 +1,49 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/chrlesur/ontology-server/internal/alerts"
+	"github.com/chrlesur/ontology-server/internal/graph"
 	"github.com/chrlesur/ontology-server/internal/logger"
 	"github.com/chrlesur/ontology-server/internal/models"
+	"github.com/chrlesur/ontology-server/internal/parser"
 	"github.com/chrlesur/ontology-server/internal/search"
+	"github.com/chrlesur/ontology-server/internal/sparql"
 	"github.com/chrlesur/ontology-server/internal/storage"
 	"github.com/gin-gonic/gin"
 )
 
+// ndjsonContentType est le type de contenu des résultats de recherche transmis en flux
+const ndjsonContentType = "application/x-ndjson"
+
+// Types de contenu du protocole SPARQL
+const (
+	sparqlQueryContentType   = "application/sparql-query"
+	sparqlResultsContentType = "application/sparql-results+json"
+	nTriplesContentType      = "application/n-triples"
+)
+
 // Handler encapsule les dépendances nécessaires pour gérer les requêtes API
 type Handler struct {
 	Storage *storage.MemoryStorage
 	Logger  *logger.Logger
 	Search  *search.SearchEngine
+	// Alerts gère les recherches enregistrées, évaluées à chaque chargement d'ontologie
+	Alerts *alerts.Manager
+	// Graph indexe les relations des ontologies pour les requêtes de voisinage
+	Graph *graph.Graph
+	// Sparql répond aux requêtes SPARQL sur les ontologies exposées en triplets RDF
+	Sparql *sparql.Service
 }
 
 type UniqueResult struct {
@@ -32,11 +55,13 @@ type UniqueResult struct {
 	FileID         string
 	SourceFile     string
 	SourceMetadata *models.SourceMetadata
+	ContextMatches []search.ContextMatch
+	Explanation    *search.Explanation
 }
 
 // NewHandler crée une nouvelle instance de Handler avec le stockage, le logger et le moteur de recherche fournis
 func NewHandler(storage *storage.MemoryStorage, logger *logger.Logger, search *search.SearchEngine) *Handler {
-	return &Handler{Storage: storage, Logger: logger, Search: search}
+	return &Handler{Storage: storage, Logger: logger, Search: search, Graph: search.Graph, Sparql: sparql.New(storage, search.Graph)}
 }
 
 // GetOntology récupère une ontologie par son ID
@@ -160,19 +185,50 @@ func (h *Handler) ListOntologies(c *gin.Context) {
 // SearchOntologies effectue une recherche dans les ontologies
 func (h *Handler) SearchOntologies(c *gin.Context) {
 	query := c.Query("q")
-	fileID := c.Query("file_id")
-	ontologyID := c.Query("ontology_id")
-	elementType := c.Query("type")
-	contextSize := 5 // Valeur par défaut, vous pouvez la rendre configurable si nécessaire
 
 	if query == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
 		return
 	}
 
-	h.Logger.Info(fmt.Sprintf("Searching ontologies with query: %s, fileID: %s", query, fileID))
+	// Nombre de mots de contexte avant et après l'élément ; 0 conserve les contextes stockés
+	contextSize := 0
+	if rawSize := c.Query("context_size"); rawSize != "" {
+		size, err := strconv.Atoi(rawSize)
+		if err != nil || size < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'context_size' must be a non-negative integer"})
+			return
+		}
+		contextSize = size
+	}
 
-	results, err := h.Search.Search(query, ontologyID, elementType, contextSize, fileID)
+	opts := searchOptionsFromQuery(c, query, contextSize)
+	opts.WithFacets = c.Query("facets") == "true"
+	opts.SearchContexts = c.Query("in_contexts") == "true"
+	opts.Explain = c.Query("explain") == "true"
+	switch mode := c.DefaultQuery("mode", search.SearchModeLexical); mode {
+	case search.SearchModeLexical, search.SearchModeSemantic, search.SearchModeHybrid,
+		search.SearchModeRegex, search.SearchModeWildcard:
+		opts.Mode = mode
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'mode' must be one of lexical, semantic, hybrid, regex or wildcard"})
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("Searching ontologies with query: %s, fileIDs: %v", query, opts.FileIDs))
+
+	if strings.Contains(c.GetHeader("Accept"), ndjsonContentType) {
+		h.streamSearch(c, opts)
+		return
+	}
+
+	// La recherche s'interrompt si le client se déconnecte
+	searchResponse, err := h.Search.SearchWithOptions(c.Request.Context(), opts)
+	if errors.Is(err, search.ErrInvalidPattern) {
+		h.Logger.Warning(fmt.Sprintf("Rejected search pattern %s: %v", query, err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		h.Logger.Error(fmt.Sprintf("Error during search: %v", err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred during the search"})
@@ -180,34 +236,519 @@ func (h *Handler) SearchOntologies(c *gin.Context) {
 	}
 
 	// Log des résultats côté serveur
-	h.Logger.Info(fmt.Sprintf("Search results: %+v", results))
+	h.Logger.Info(fmt.Sprintf("Search results: %+v", searchResponse.Results))
+
+	finalResults := h.buildSearchResponse(searchResponse.Results, opts.FileIDs)
+
+	// La réponse reste un tableau, sauf si le client demande les facettes ou les suggestions
+	suggest := c.Query("suggest") == "true"
+	response := gin.H{"results": finalResults}
+	envelope := opts.WithFacets || suggest
+	if opts.WithFacets {
+		response["facets"] = searchResponse.Facets
+	}
+	// L'en-tête signale une réponse partielle sans changer la forme de la réponse
+	if searchResponse.Partial {
+		h.Logger.Warning(fmt.Sprintf("Search for %s interrupted, returning partial results", query))
+		c.Header("X-Search-Partial", "true")
+		response["partial"] = true
+	}
+	// Les corrections orthographiques n'ont pas de sens pour un motif
+	if suggest && len(finalResults) == 0 && !searchResponse.Partial && opts.Mode != search.SearchModeRegex && opts.Mode != search.SearchModeWildcard {
+		if alternatives := h.Search.DidYouMean(query, opts.OntologyIDs, 5); len(alternatives) > 0 {
+			h.Logger.Info(fmt.Sprintf("No result for %s, suggesting: %v", query, alternatives))
+			response["did_you_mean"] = alternatives
+		}
+	}
 
+	if envelope {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	c.JSON(http.StatusOK, finalResults)
+}
+
+// streamSearch écrit les résultats au format NDJSON, un résultat par ligne, suivis d'une ligne
+// de synthèse {"done": true, "count": ..., "partial": ...}. Par défaut, les résultats sont triés par
+// pertinence : la recherche est terminée avant l'écriture de la première ligne. Seul unsorted=true
+// transmet réellement en flux, chaque résultat étant écrit dès qu'il est évalué.
+func (h *Handler) streamSearch(c *gin.Context, opts search.SearchOptions) {
+	sorted := c.Query("unsorted") != "true"
+	encoder := json.NewEncoder(c.Writer)
+	started := false
+	start := func() {
+		if !started {
+			c.Header("Content-Type", ndjsonContentType)
+			c.Status(http.StatusOK)
+			started = true
+		}
+	}
+
+	count := 0
+	response, err := h.Search.SearchStream(c.Request.Context(), opts, sorted, func(result search.SearchResult) error {
+		results := h.buildSearchResponse([]search.SearchResult{result}, opts.FileIDs)
+		if len(results) == 0 {
+			return nil
+		}
+		start()
+		if err := encoder.Encode(results[0]); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		count++
+		return nil
+	})
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error during streamed search: %v", err))
+		if started {
+			// L'en-tête est déjà envoyé : le client constate la fin prématurée du flux
+			return
+		}
+		if errors.Is(err, search.ErrInvalidPattern) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred during the search"})
+		return
+	}
+
+	start()
+	summary := gin.H{"done": true, "count": count, "partial": response.Partial}
+	if opts.WithFacets {
+		summary["facets"] = response.Facets
+	}
+	if err := encoder.Encode(summary); err != nil {
+		h.Logger.Error(fmt.Sprintf("Error writing search summary: %v", err))
+	}
+	h.Logger.Info(fmt.Sprintf("Streamed %d results for query: %s", count, opts.Query))
+}
+
+// SearchFacets retourne uniquement les décomptes par facette, la requête textuelle étant optionnelle
+func (h *Handler) SearchFacets(c *gin.Context) {
+	opts := searchOptionsFromQuery(c, c.Query("q"), 0)
+	opts.WithFacets = true
+
+	h.Logger.Info(fmt.Sprintf("Computing search facets for query: %s", opts.Query))
+
+	searchResponse, err := h.Search.SearchWithOptions(c.Request.Context(), opts)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error computing facets: %v", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred during the search"})
+		return
+	}
+
+	if searchResponse.Partial {
+		c.Header("X-Search-Partial", "true")
+	}
+	c.JSON(http.StatusOK, searchResponse.Facets)
+}
+
+// Suggest retourne des complétions pour le préfixe saisi dans la barre de recherche
+func (h *Handler) Suggest(c *gin.Context) {
+	prefix := c.Query("prefix")
+	if strings.TrimSpace(prefix) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'prefix' is required"})
+		return
+	}
+
+	limit := 10
+	if rawLimit := c.Query("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil || parsed <= 0 || parsed > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'limit' must be between 1 and 50"})
+			return
+		}
+		limit = parsed
+	}
+
+	suggestions := h.Search.Suggest(prefix, queryValues(c, "ontology_id"), queryValues(c, "type"), limit)
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// ListSynonyms liste les ensembles de synonymes globaux ou d'une ontologie (paramètre ontology_id)
+func (h *Handler) ListSynonyms(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Search.Synonyms.Sets(c.Query("ontology_id")))
+}
+
+// AddSynonyms ajoute un ensemble de synonymes
+func (h *Handler) AddSynonyms(c *gin.Context) {
+	var set search.SynonymSet
+	if err := c.ShouldBindJSON(&set); err != nil {
+		h.Logger.Error(fmt.Sprintf("Error decoding synonym set: %v", err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": MsgInvalidInput})
+		return
+	}
+
+	if err := h.Search.Synonyms.Add(set.OntologyID, set.Terms); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("Added synonym set %v (ontology: %q)", set.Terms, set.OntologyID))
+	c.JSON(http.StatusCreated, set)
+}
+
+// LoadSynonyms charge un fichier TSV de synonymes, globaux ou propres à l'ontologie indiquée
+func (h *Handler) LoadSynonyms(c *gin.Context) {
+	synonymsFile, err := c.FormFile("synonymsFile")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No synonyms file uploaded"})
+		return
+	}
+	ontologyID := c.PostForm("ontology_id")
+
+	tempFile := filepath.Join(os.TempDir(), filepath.Base(synonymsFile.Filename))
+	if err := c.SaveUploadedFile(synonymsFile, tempFile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save synonyms file"})
+		return
+	}
+	defer os.Remove(tempFile)
+
+	sets, err := parser.ParseSynonymsTSV(tempFile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to parse synonyms file: %v", err)})
+		return
+	}
+
+	loaded := h.Search.Synonyms.Load(ontologyID, sets)
+	h.Logger.Info(fmt.Sprintf("Loaded %d synonym sets (ontology: %q)", loaded, ontologyID))
+	c.JSON(http.StatusOK, gin.H{"message": "Synonyms loaded successfully", "sets": loaded})
+}
+
+// ClearSynonyms supprime les synonymes globaux ou ceux d'une ontologie (paramètre ontology_id)
+func (h *Handler) ClearSynonyms(c *gin.Context) {
+	h.Search.Synonyms.Clear(c.Query("ontology_id"))
+	c.Status(http.StatusNoContent)
+}
+
+// SearchCacheStats retourne l'état et les compteurs du cache de résultats de recherche
+func (h *Handler) SearchCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Search.Cache.Stats())
+}
+
+// ClearSearchCache vide le cache de résultats de recherche
+func (h *Handler) ClearSearchCache(c *gin.Context) {
+	h.Search.Cache.Clear()
+	h.Logger.Info("Search cache cleared")
+	c.Status(http.StatusNoContent)
+}
+
+// ListSavedSearches liste les recherches enregistrées
+func (h *Handler) ListSavedSearches(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Alerts.List())
+}
+
+// CreateSavedSearch enregistre une recherche à réévaluer à chaque chargement d'ontologie
+func (h *Handler) CreateSavedSearch(c *gin.Context) {
+	var body alerts.SavedSearch
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.Logger.Error(fmt.Sprintf("Error decoding saved search: %v", err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": MsgInvalidInput})
+		return
+	}
+	switch body.Mode {
+	case "", search.SearchModeLexical, search.SearchModeSemantic, search.SearchModeHybrid,
+		search.SearchModeRegex, search.SearchModeWildcard:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field 'mode' must be one of lexical, semantic, hybrid, regex or wildcard"})
+		return
+	}
+	// Un motif invalide échouerait à chaque évaluation : il est refusé dès l'enregistrement
+	if err := search.ValidatePattern(body.Mode, body.Query); err != nil {
+		h.Logger.Warning(fmt.Sprintf("Rejected saved search pattern %s: %v", body.Query, err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := h.Alerts.Create(body)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error creating saved search: %v", err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, saved)
+}
+
+// GetSavedSearch retourne une recherche enregistrée avec sa boîte de réception
+func (h *Handler) GetSavedSearch(c *gin.Context) {
+	saved, err := h.Alerts.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": MsgResourceNotFound})
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteSavedSearch supprime une recherche enregistrée
+func (h *Handler) DeleteSavedSearch(c *gin.Context) {
+	h.savedSearchAction(c, h.Alerts.Delete)
+}
+
+// GetSavedSearchInbox retourne les correspondances détectées pour une recherche enregistrée
+func (h *Handler) GetSavedSearchInbox(c *gin.Context) {
+	saved, err := h.Alerts.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": MsgResourceNotFound})
+		return
+	}
+	c.JSON(http.StatusOK, saved.Inbox)
+}
+
+// ClearSavedSearchInbox vide la boîte de réception d'une recherche enregistrée
+func (h *Handler) ClearSavedSearchInbox(c *gin.Context) {
+	h.savedSearchAction(c, h.Alerts.ClearInbox)
+}
+
+// savedSearchAction applique une opération à une recherche enregistrée et traduit son erreur en statut HTTP
+func (h *Handler) savedSearchAction(c *gin.Context, action func(id string) error) {
+	err := action(c.Param("id"))
+	switch {
+	case errors.Is(err, alerts.ErrSavedSearchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": MsgResourceNotFound})
+	case err != nil:
+		h.Logger.Error(fmt.Sprintf("Error updating saved search: %v", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": MsgInternalServerError})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// SetElementAltLabels remplace les libellés alternatifs d'un élément d'une ontologie
+func (h *Handler) SetElementAltLabels(c *gin.Context) {
+	ontologyID := c.Param("id")
+	elementName := c.Param("element_name")
+
+	var body struct {
+		Labels []string `json:"labels"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.Logger.Error(fmt.Sprintf("Error decoding alternate labels: %v", err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": MsgInvalidInput})
+		return
+	}
+
+	element, err := h.Storage.SetElementAltLabels(ontologyID, elementName, body.Labels)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error setting alternate labels: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": MsgResourceNotFound})
+		return
+	}
+
+	c.JSON(http.StatusOK, element)
+}
+
+// GetRelationTypes retourne les déclarations des types de relation d'une ontologie et les relations
+// qui ne respectent pas le domaine ou l'image de leur type
+func (h *Handler) GetRelationTypes(c *gin.Context) {
+	id := c.Param("id")
+
+	types, violations, err := h.Graph.RelationTypes(id)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error getting relation types: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ontology not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"relation_types": types, "violations": violations})
+}
+
+// SetRelationTypes remplace les déclarations des types de relation d'une ontologie
+// (inverse, symétrique, transitive, domaine et image)
+func (h *Handler) SetRelationTypes(c *gin.Context) {
+	id := c.Param("id")
+
+	var types []*models.RelationType
+	if err := c.ShouldBindJSON(&types); err != nil {
+		h.Logger.Error(fmt.Sprintf("Error decoding relation types: %v", err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": MsgInvalidInput})
+		return
+	}
+	for _, t := range types {
+		if t == nil || strings.TrimSpace(t.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Every relation type needs a name"})
+			return
+		}
+	}
+
+	if err := h.Storage.SetRelationTypes(id, types); err != nil {
+		h.Logger.Error(fmt.Sprintf("Error setting relation types: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ontology not found"})
+		return
+	}
+
+	h.GetRelationTypes(c)
+}
+
+// GetTypeTree retourne la hiérarchie des types d'éléments d'une ontologie avec le nombre d'éléments par type
+func (h *Handler) GetTypeTree(c *gin.Context) {
+	id := c.Param("id")
+
+	tree, err := h.Storage.GetTypeTree(id)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error getting type tree: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ontology not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tree)
+}
+
+// SetTypeParents déclare la taxonomie des types d'une ontologie, sous la forme {"type": "type parent"}
+func (h *Handler) SetTypeParents(c *gin.Context) {
+	id := c.Param("id")
+
+	var parents map[string]string
+	if err := c.ShouldBindJSON(&parents); err != nil {
+		h.Logger.Error(fmt.Sprintf("Error decoding taxonomy: %v", err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": MsgInvalidInput})
+		return
+	}
+
+	if err := h.Storage.SetTypeParents(id, parents); err != nil {
+		h.Logger.Error(fmt.Sprintf("Error setting taxonomy: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ontology not found"})
+		return
+	}
+
+	h.GetTypeTree(c)
+}
+
+// GetRules retourne les règles d'inférence d'une ontologie
+func (h *Handler) GetRules(c *gin.Context) {
+	id := c.Param("id")
+
+	ontology, err := h.Storage.GetOntology(id)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error getting rules: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ontology not found"})
+		return
+	}
+
+	rules := ontology.Rules
+	if rules == nil {
+		rules = []*models.Rule{}
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// SetRules remplace les règles d'inférence d'une ontologie ; les relations déduites sont recalculées
+func (h *Handler) SetRules(c *gin.Context) {
+	id := c.Param("id")
+
+	var rules []*models.Rule
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		h.Logger.Error(fmt.Sprintf("Error decoding rules: %v", err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": MsgInvalidInput})
+		return
+	}
+	for _, rule := range rules {
+		if rule == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": MsgInvalidInput})
+			return
+		}
+		if err := graph.ParseRule(rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid rule: %v", err)})
+			return
+		}
+	}
+
+	if err := h.Storage.SetRules(id, rules); err != nil {
+		h.Logger.Error(fmt.Sprintf("Error setting rules: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ontology not found"})
+		return
+	}
+
+	h.GetRules(c)
+}
+
+// GetInferredRelations retourne les relations déduites d'une ontologie avec leur explication
+func (h *Handler) GetInferredRelations(c *gin.Context) {
+	id := c.Param("id")
+
+	relations, err := h.Graph.InferredRelations(id)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error getting inferred relations: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ontology not found"})
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("Found %d inferred relations in ontology %s", len(relations), id))
+	c.JSON(http.StatusOK, relations)
+}
+
+// GetUnresolvedRelations retourne les extrémités de relation qui n'ont été rattachées à aucun élément
+// à l'ajout ou à la mise à jour de l'ontologie, avec les éléments les plus proches
+func (h *Handler) GetUnresolvedRelations(c *gin.Context) {
+	id := c.Param("id")
+
+	ontology, err := h.Storage.GetOntology(id)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error getting ontology: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ontology not found"})
+		return
+	}
+
+	unresolved := ontology.UnresolvedEndpoints
+	if unresolved == nil {
+		unresolved = []*models.UnresolvedEndpoint{}
+	}
+	h.Logger.Info(fmt.Sprintf("Found %d unresolved relation endpoints in ontology %s", len(unresolved), id))
+	c.JSON(http.StatusOK, gin.H{
+		"ontology_id": id,
+		"relations":   len(ontology.Relations),
+		"unresolved":  unresolved,
+	})
+}
+
+// searchOptionsFromQuery construit les options de recherche à partir des filtres de la requête HTTP
+func searchOptionsFromQuery(c *gin.Context, query string, contextSize int) search.SearchOptions {
+	return search.SearchOptions{
+		Query:         query,
+		OntologyIDs:   queryValues(c, "ontology_id"),
+		ElementTypes:  queryValues(c, "type"),
+		FileIDs:       queryValues(c, "file_id"),
+		RelationTypes: queryValues(c, "relation_type"),
+		ContextSize:   contextSize,
+	}
+}
+
+// queryValues récupère les valeurs d'un paramètre répété ou séparé par des virgules
+func queryValues(c *gin.Context, name string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(name) {
+		for _, value := range strings.Split(raw, ",") {
+			value = strings.TrimSpace(value)
+			if value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// buildSearchResponse dédoublonne les résultats et les enrichit avec les informations de fichier source
+func (h *Handler) buildSearchResponse(results []search.SearchResult, fileIDs []string) []gin.H {
 	// Utiliser une map pour stocker les résultats uniques
 	uniqueResults := make(map[string]*UniqueResult)
+	var order []string
 
 	for _, result := range results {
-		element, err := h.Storage.GetElement(result.ElementName)
-		if err == nil && element != nil {
-			ontology, _ := h.Storage.GetOntology(result.OntologyID)
+		// L'élément et les métadonnées de son ontologie accompagnent le résultat
+		if element := result.Element; element != nil {
 			var sourceFile string
 			var resultFileID string
 			var sourceMetadata *models.SourceMetadata
-			if ontology != nil && ontology.Source != nil {
-				sourceMetadata = ontology.Source
-				// Utiliser le fileID de la requête s'il est fourni, sinon chercher dans les contextes
-				if fileID != "" {
-					if fileInfo, exists := sourceMetadata.Files[fileID]; exists {
-						resultFileID = fileID
-						sourceFile = fileInfo.SourceFile
+			if result.Source != nil {
+				sourceMetadata = result.Source
+				// Retenir le premier contexte appartenant aux fichiers filtrés, s'il y en a
+				for _, context := range result.Contexts {
+					if len(fileIDs) > 0 && !containsString(fileIDs, context.FileID) {
+						continue
 					}
-				} else {
-					// Logique existante pour trouver le FileID
-					for _, context := range element.Contexts {
-						if fileInfo, exists := sourceMetadata.Files[context.FileID]; exists {
-							resultFileID = context.FileID
-							sourceFile = fileInfo.SourceFile
-							break
-						}
+					if fileInfo, exists := sourceMetadata.Files[context.FileID]; exists {
+						resultFileID = context.FileID
+						sourceFile = fileInfo.SourceFile
+						break
 					}
 				}
 				h.Logger.Info(fmt.Sprintf("File info for %s: ID=%s, SourceFile=%s", result.ElementName, resultFileID, sourceFile))
@@ -217,11 +758,6 @@ func (h *Handler) SearchOntologies(c *gin.Context) {
 				result.ElementName, result.ElementType, result.Description))
 			h.Logger.Info(fmt.Sprintf("Stored element %s: Type=%s, Description=%s",
 				element.Name, element.Type, element.Description))
-			// Utiliser la description de l'élément stocké si disponible
-			description := element.Description
-			if description == "" {
-				description = result.Description
-			}
 			// Créer ou mettre à jour le résultat unique
 			key := result.ElementName + "|" + result.OntologyID
 			if _, exists := uniqueResults[key]; !exists {
@@ -230,11 +766,14 @@ func (h *Handler) SearchOntologies(c *gin.Context) {
 					ElementType:    result.ElementType,
 					Description:    result.Description,
 					OntologyID:     result.OntologyID,
-					Contexts:       element.Contexts,
+					Contexts:       result.Contexts,
 					FileID:         resultFileID,
 					SourceFile:     sourceFile,
 					SourceMetadata: sourceMetadata,
+					ContextMatches: result.ContextMatches,
+					Explanation:    result.Explanation,
 				}
+				order = append(order, key)
 			}
 
 			h.Logger.Info(fmt.Sprintf("Added/Updated unique result for %s: Type=%s, Description=%s",
@@ -242,9 +781,10 @@ func (h *Handler) SearchOntologies(c *gin.Context) {
 		}
 	}
 
-	// Convertir les résultats uniques en slice pour la réponse JSON
+	// Convertir les résultats uniques en slice pour la réponse JSON, dans l'ordre de pertinence
 	finalResults := make([]gin.H, 0, len(uniqueResults))
-	for _, ur := range uniqueResults {
+	for _, key := range order {
+		ur := uniqueResults[key]
 		resultMap := gin.H{
 			"ElementName": ur.ElementName,
 			"ElementType": ur.ElementType,
@@ -255,6 +795,14 @@ func (h *Handler) SearchOntologies(c *gin.Context) {
 			"SourceFile":  ur.SourceFile,
 		}
 
+		if len(ur.ContextMatches) > 0 {
+			resultMap["ContextMatches"] = ur.ContextMatches
+		}
+
+		if ur.Explanation != nil {
+			resultMap["Explanation"] = ur.Explanation
+		}
+
 		if ur.SourceMetadata != nil {
 			resultMap["SourceMetadata"] = gin.H{
 				"ontology_file":   ur.SourceMetadata.OntologyFile,
@@ -266,7 +814,16 @@ func (h *Handler) SearchOntologies(c *gin.Context) {
 		finalResults = append(finalResults, resultMap)
 	}
 
-	c.JSON(http.StatusOK, finalResults)
+	return finalResults
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 // ElementDetailsHandler récupère les détails d'un élément spécifique
@@ -292,6 +849,271 @@ func (h *Handler) ElementDetailsHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, element)
 }
 
+// SimilarElements retourne les éléments les plus proches d'un élément, toutes ontologies confondues
+func (h *Handler) SimilarElements(c *gin.Context) {
+	elementName := c.Param("id")
+
+	limit := 10
+	if rawLimit := c.Query("limit"); rawLimit != "" {
+		value, err := strconv.Atoi(rawLimit)
+		if err != nil || value < 1 || value > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'limit' must be an integer between 1 and 50"})
+			return
+		}
+		limit = value
+	}
+
+	h.Logger.Info(fmt.Sprintf("Getting elements similar to: %s", elementName))
+
+	similar, err := h.Search.SimilarElements(elementName, limit)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error getting similar elements: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": MsgResourceNotFound})
+		return
+	}
+
+	c.JSON(http.StatusOK, similar)
+}
+
+// intQuery lit un paramètre entier compris entre min et max, ou retourne def s'il est absent.
+// En cas de valeur invalide, la réponse 400 est envoyée et ok vaut false.
+func intQuery(c *gin.Context, name string, def, min, max int) (value int, ok bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return def, true
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < min || value > max {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Query parameter '%s' must be an integer between %d and %d", name, min, max)})
+		return 0, false
+	}
+	return value, true
+}
+
+// Neighbourhood retourne les nœuds et relations atteignables depuis un élément en au plus depth sauts
+func (h *Handler) Neighbourhood(c *gin.Context) {
+	elementName := c.Param("id")
+
+	opts := graph.NeighbourhoodOptions{
+		Types:     queryValues(c, "types"),
+		Direction: c.DefaultQuery("direction", graph.DirectionBoth),
+	}
+	if !graph.ValidDirection(opts.Direction) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'direction' must be one of outgoing, incoming, both"})
+		return
+	}
+	var ok bool
+	if opts.Depth, ok = intQuery(c, "depth", graph.DefaultDepth, 1, graph.MaxDepth); !ok {
+		return
+	}
+	if opts.MaxNodes, ok = intQuery(c, "max_nodes", graph.DefaultMaxNodes, 1, 1000); !ok {
+		return
+	}
+	if opts.MaxEdges, ok = intQuery(c, "max_edges", graph.DefaultMaxEdges, 1, 5000); !ok {
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("Getting neighbourhood of %s (depth %d, direction %s)", elementName, opts.Depth, opts.Direction))
+
+	subgraph, err := h.Graph.Neighbourhood(elementName, opts)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error getting neighbourhood: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": MsgResourceNotFound})
+		return
+	}
+
+	c.JSON(http.StatusOK, subgraph)
+}
+
+// Paths retourne les chemins de relations reliant deux éléments
+func (h *Handler) Paths(c *gin.Context) {
+	from, to := c.Query("from"), c.Query("to")
+	if from == "" || to == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameters 'from' and 'to' are required"})
+		return
+	}
+
+	opts := graph.PathOptions{
+		Mode:      c.DefaultQuery("mode", graph.PathModeShortest),
+		Types:     queryValues(c, "types"),
+		Direction: c.DefaultQuery("direction", graph.DirectionBoth),
+	}
+	if !graph.ValidPathMode(opts.Mode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'mode' must be one of shortest, k_shortest, all"})
+		return
+	}
+	if !graph.ValidDirection(opts.Direction) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'direction' must be one of outgoing, incoming, both"})
+		return
+	}
+	var ok bool
+	if opts.K, ok = intQuery(c, "k", graph.DefaultK, 1, graph.MaxK); !ok {
+		return
+	}
+	if opts.MaxLength, ok = intQuery(c, "max_length", 0, 1, graph.MaxPathLength); !ok {
+		return
+	}
+	if opts.MaxPaths, ok = intQuery(c, "max_paths", graph.DefaultMaxPaths, 1, 1000); !ok {
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("Searching %s paths from %s to %s", opts.Mode, from, to))
+
+	result, err := h.Graph.Paths(from, to, opts)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error searching paths: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": MsgResourceNotFound})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// OntologyAnalytics retourne les centralités, composantes connexes et communautés du graphe d'une ontologie
+func (h *Handler) OntologyAnalytics(c *gin.Context) {
+	id := c.Param("id")
+
+	// top limite le nombre de nœuds classés par centralité, 0 pour tous
+	top, ok := intQuery(c, "top", 20, 0, 100000)
+	if !ok {
+		return
+	}
+
+	analytics, err := h.Graph.Analytics(id)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error computing analytics: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ontology not found"})
+		return
+	}
+
+	// Copier l'analyse conservée en cache avant de tronquer le classement
+	response := *analytics
+	if top > 0 && len(response.Centrality) > top {
+		response.Centrality = response.Centrality[:top]
+	}
+	h.Logger.Info(fmt.Sprintf("Computed analytics for ontology %s: %d nodes, %d components, %d communities",
+		id, response.Nodes, len(response.Components), len(response.Communities)))
+	c.JSON(http.StatusOK, response)
+}
+
+// PatternQuery exécute une requête de motifs sur le graphe des relations, transmise par le
+// paramètre q (GET) ou par le champ query du corps JSON (POST), et retourne la table des liaisons
+func (h *Handler) PatternQuery(c *gin.Context) {
+	var body struct {
+		Query       string   `json:"query"`
+		OntologyIDs []string `json:"ontology_ids"`
+	}
+	if c.Request.Method == http.MethodPost {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			h.Logger.Error(fmt.Sprintf("Error decoding query: %v", err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": MsgInvalidInput})
+			return
+		}
+	} else {
+		body.Query = c.Query("q")
+		body.OntologyIDs = queryValues(c, "ontology_id")
+	}
+	if strings.TrimSpace(body.Query) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A query is required"})
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("Executing pattern query: %s", body.Query))
+
+	result, err := h.Graph.Query(body.Query, body.OntologyIDs)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error executing pattern query: %v", err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("Pattern query returned %d rows", len(result.Rows)))
+	c.JSON(http.StatusOK, result)
+}
+
+// SPARQL exécute une requête SPARQL transmise par le paramètre query (GET), par un corps
+// application/sparql-query ou par le champ de formulaire query (POST). Les résultats SELECT et ASK
+// sont retournés au format SPARQL JSON, le graphe d'un CONSTRUCT au format N-Triples.
+func (h *Handler) SPARQL(c *gin.Context) {
+	query := c.Query("query")
+	if c.Request.Method == http.MethodPost {
+		if strings.HasPrefix(c.ContentType(), sparqlQueryContentType) {
+			body, err := c.GetRawData()
+			if err != nil {
+				h.Logger.Error(fmt.Sprintf("Error reading SPARQL query: %v", err))
+				c.JSON(http.StatusBadRequest, gin.H{"error": MsgInvalidInput})
+				return
+			}
+			query = string(body)
+		} else {
+			query = c.PostForm("query")
+		}
+	}
+	if strings.TrimSpace(query) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A query is required"})
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("Executing SPARQL query: %s", query))
+
+	result, err := h.Sparql.Query(query)
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error executing SPARQL query: %v", err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch result.Form {
+	case sparql.FormConstruct:
+		h.Logger.Info(fmt.Sprintf("SPARQL query constructed %d triples", len(result.Triples)))
+		c.Data(http.StatusOK, nTriplesContentType, []byte(result.NTriples()))
+	default:
+		h.Logger.Info(fmt.Sprintf("SPARQL query returned %d solutions", len(result.Bindings)))
+		body, err := json.Marshal(result.JSON())
+		if err != nil {
+			h.Logger.Error(fmt.Sprintf("Error encoding SPARQL results: %v", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": MsgInternalServerError})
+			return
+		}
+		c.Data(http.StatusOK, sparqlResultsContentType, body)
+	}
+}
+
+// GetFileElements retourne les éléments présents dans une plage de mots d'un fichier source, triés par position
+func (h *Handler) GetFileElements(c *gin.Context) {
+	fileID := c.Param("fileId")
+
+	// Bornes incluses, -1 pour une plage ouverte
+	bounds := map[string]int{"from": -1, "to": -1}
+	for name := range bounds {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Query parameter '%s' must be a non-negative integer", name)})
+			return
+		}
+		bounds[name] = value
+	}
+	if bounds["from"] >= 0 && bounds["to"] >= 0 && bounds["from"] > bounds["to"] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'from' must not exceed 'to'"})
+		return
+	}
+
+	h.Logger.Info(fmt.Sprintf("Getting elements of file %s between %d and %d", fileID, bounds["from"], bounds["to"]))
+
+	elements, err := h.Storage.GetFileElements(fileID, bounds["from"], bounds["to"])
+	if err != nil {
+		h.Logger.Error(fmt.Sprintf("Error getting file elements: %v", err))
+		c.JSON(http.StatusNotFound, gin.H{"error": MsgResourceNotFound})
+		return
+	}
+
+	c.JSON(http.StatusOK, elements)
+}
+
 func (h *Handler) LoadOntology(c *gin.Context) {
 	// Fichier d'ontologie principal
 	ontologyFile, err := c.FormFile("ontologyFile")
@@ -360,9 +1182,9 @@ func (h *Handler) GetElementRelations(c *gin.Context) {
 
 	h.Logger.Info(fmt.Sprintf("Getting relations for element: %s", elementName))
 
-	relations, err := h.Storage.GetElementRelations(elementName)
-	if err != nil {
-		h.Logger.Error(fmt.Sprintf("Error getting element relations: %v", err))
+	relations := h.Graph.Relations(elementName)
+	if len(relations) == 0 {
+		h.Logger.Info(fmt.Sprintf("No relations found for element: %s", elementName))
 		// Retourner un tableau vide avec status 200 si aucune relation n'est trouvée
 		c.JSON(http.StatusOK, []models.Relation{})
 		return
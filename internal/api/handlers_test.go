@@ -11,7 +11,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/chrlesur/ontology-server/internal/alerts"
 	"github.com/chrlesur/ontology-server/internal/logger"
 	"github.com/chrlesur/ontology-server/internal/models"
 	"github.com/chrlesur/ontology-server/internal/search"
@@ -273,3 +275,678 @@ func TestViewSourceFile(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchFacets(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID:   "test1",
+		Name: "Test Ontology",
+		Elements: []*models.OntologyElement{
+			{Name: "Test Element", Type: "Concept"},
+			{Name: "Other Element", Type: "Rôle"},
+		},
+	})
+
+	router.GET("/search/facets", h.SearchFacets)
+
+	req, _ := http.NewRequest("GET", "/search/facets?type=Concept", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var facets search.Facets
+	if err := json.Unmarshal(w.Body.Bytes(), &facets); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(facets.ElementTypes) != 2 {
+		t.Errorf("Expected 2 element type facets, got %d", len(facets.ElementTypes))
+	}
+	if len(facets.Ontologies) != 1 || facets.Ontologies[0].Count != 1 {
+		t.Errorf("Expected 1 Concept element in ontology facet, got %+v", facets.Ontologies)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID:       "test1",
+		Elements: []*models.OntologyElement{{Name: "Test_Element", Type: "Concept"}},
+	})
+
+	router.GET("/suggest", h.Suggest)
+
+	req, _ := http.NewRequest("GET", "/suggest?prefix=test%20el", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var suggestions []search.Suggestion
+	if err := json.Unmarshal(w.Body.Bytes(), &suggestions); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].Name != "Test_Element" {
+		t.Errorf("Expected Test_Element suggestion, got %+v", suggestions)
+	}
+
+	req, _ = http.NewRequest("GET", "/suggest", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without prefix, got %d", w.Code)
+	}
+}
+
+func TestSearchDidYouMean(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID:       "test1",
+		Elements: []*models.OntologyElement{{Name: "Fonctionnaire_Titulaire", Type: "Rôle", Description: "Agent de la fonction publique"}},
+	})
+
+	router.GET("/search", h.SearchOntologies)
+
+	// Sans suggest=true, une recherche sans résultat retourne toujours un tableau
+	req, _ := http.NewRequest("GET", "/search?q=fonksionnèr", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Fatalf("Expected an empty array, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/search?q=fonksionnèr&suggest=true", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		Results    []map[string]interface{} `json:"results"`
+		DidYouMean []string                 `json:"did_you_mean"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Results) != 0 {
+		t.Fatalf("Expected no result, got %d", len(response.Results))
+	}
+	if len(response.DidYouMean) == 0 || response.DidYouMean[0] != "fonctionnaire" {
+		t.Errorf("Expected 'fonctionnaire' suggestion, got %v", response.DidYouMean)
+	}
+}
+
+func TestSearchPartialKeepsArray(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID:       "test1",
+		Elements: []*models.OntologyElement{{Name: "Service_Public", Type: "Concept"}},
+	})
+	// Un délai déjà écoulé interrompt la recherche avant le premier élément
+	h.Search.Timeout = time.Nanosecond
+
+	router.GET("/search", h.SearchOntologies)
+
+	req, _ := http.NewRequest("GET", "/search?q=service", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("X-Search-Partial") != "true" {
+		t.Fatalf("Expected a partial response, got %d with headers %v", w.Code, w.Header())
+	}
+	if body := strings.TrimSpace(w.Body.String()); !strings.HasPrefix(body, "[") {
+		t.Errorf("Expected an array for a partial search, got %s", body)
+	}
+}
+
+func TestSearchPatternModes(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID: "test1",
+		Elements: []*models.OntologyElement{
+			{Name: "Agent_Service_Public", Type: "Rôle"},
+			{Name: "Service_Juridique", Type: "Organisation"},
+		},
+	})
+
+	router.GET("/search", h.SearchOntologies)
+
+	req, _ := http.NewRequest("GET", "/search?mode=wildcard&q="+url.QueryEscape("*_Juridique"), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var results []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(results) != 1 || results[0]["ElementName"] != "Service_Juridique" {
+		t.Errorf("Expected only Service_Juridique, got %v", results)
+	}
+
+	req, _ = http.NewRequest("GET", "/search?mode=regex&q="+url.QueryEscape("Agent_("), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid pattern, got %d", w.Code)
+	}
+}
+
+func TestSynonymsAndAltLabels(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID:       "test1",
+		Elements: []*models.OntologyElement{{Name: "Agent_Service_Public", Type: "Rôle"}},
+	})
+
+	router.POST("/synonyms", h.AddSynonyms)
+	router.GET("/synonyms", h.ListSynonyms)
+	router.PUT("/ontologies/:id/elements/:element_name/labels", h.SetElementAltLabels)
+
+	body := `{"ontology_id": "test1", "terms": ["fonctionnaire", "agent service public"]}`
+	req, _ := http.NewRequest("POST", "/synonyms", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/synonyms?ontology_id=test1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var sets []search.SynonymSet
+	if err := json.Unmarshal(w.Body.Bytes(), &sets); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(sets) != 1 || len(sets[0].Terms) != 2 {
+		t.Errorf("Expected one synonym set of two terms, got %+v", sets)
+	}
+
+	req, _ = http.NewRequest("PUT", "/ontologies/test1/elements/Agent_Service_Public/labels", strings.NewReader(`{"labels": ["Agent public"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	ontology, _ := h.Storage.GetOntology("test1")
+	if labels := ontology.Elements[0].AltLabels; len(labels) != 1 || labels[0] != "Agent public" {
+		t.Errorf("Expected alternate label to be stored, got %v", labels)
+	}
+
+	req, _ = http.NewRequest("PUT", "/ontologies/test1/elements/Unknown/labels", strings.NewReader(`{"labels": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown element, got %d", w.Code)
+	}
+}
+
+func TestSearchNDJSONStream(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID: "test1",
+		Elements: []*models.OntologyElement{
+			{Name: "Service_Public", Type: "Concept"},
+			{Name: "Agent_Service_Public", Type: "Rôle"},
+		},
+	})
+
+	router.GET("/search", h.SearchOntologies)
+
+	for _, query := range []string{"/search?q=service", "/search?q=service&unsorted=true"} {
+		req, _ := http.NewRequest("GET", query, nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+			t.Errorf("Expected NDJSON content type, got %s", contentType)
+		}
+
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected 2 results and a summary line, got %q", w.Body.String())
+		}
+		var summary struct {
+			Done    bool `json:"done"`
+			Count   int  `json:"count"`
+			Partial bool `json:"partial"`
+		}
+		if err := json.Unmarshal([]byte(lines[2]), &summary); err != nil {
+			t.Fatalf("Failed to unmarshal summary: %v", err)
+		}
+		if !summary.Done || summary.Count != 2 || summary.Partial {
+			t.Errorf("Unexpected summary: %+v", summary)
+		}
+	}
+}
+
+func TestNeighbourhood(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID:       "test1",
+		Elements: []*models.OntologyElement{{Name: "Conseil_Etat", Type: "Organisation"}},
+		Relations: []*models.Relation{
+			{Source: "Conseil_Etat", Type: "conseille", Target: "Gouvernement"},
+			{Source: "Gouvernement", Type: "dirige", Target: "Administration"},
+		},
+	})
+
+	router.GET("/elements/:id/neighbourhood", h.Neighbourhood)
+
+	req, _ := http.NewRequest("GET", "/elements/Conseil_Etat/neighbourhood?depth=2&direction=outgoing", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var subgraph struct {
+		Nodes []map[string]interface{} `json:"nodes"`
+		Edges []map[string]interface{} `json:"edges"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &subgraph); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(subgraph.Nodes) != 3 || len(subgraph.Edges) != 2 {
+		t.Errorf("Expected 3 nodes and 2 edges, got %v", subgraph)
+	}
+
+	for _, query := range []string{"depth=0", "depth=6", "direction=sideways", "max_nodes=abc"} {
+		req, _ = http.NewRequest("GET", "/elements/Conseil_Etat/neighbourhood?"+query, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+
+	req, _ = http.NewRequest("GET", "/elements/Inconnu/neighbourhood", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown element, got %d", w.Code)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID: "test1",
+		Relations: []*models.Relation{
+			{Source: "Joueuse", Type: "membre_de", Target: "Federation"},
+			{Source: "Federation", Type: "respecte", Target: "Neutralite"},
+		},
+	})
+
+	router.GET("/paths", h.Paths)
+
+	req, _ := http.NewRequest("GET", "/paths?from=Joueuse&to=Neutralite&mode=k_shortest&k=2", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var result struct {
+		Paths []struct {
+			Nodes []string `json:"nodes"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(result.Paths) != 1 || strings.Join(result.Paths[0].Nodes, ",") != "Joueuse,Federation,Neutralite" {
+		t.Errorf("Expected the path through Federation, got %+v", result.Paths)
+	}
+
+	for _, query := range []string{"from=Joueuse", "from=Joueuse&to=Neutralite&mode=longest", "from=Joueuse&to=Neutralite&k=50"} {
+		req, _ = http.NewRequest("GET", "/paths?"+query, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestOntologyAnalytics(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID: "test1",
+		Relations: []*models.Relation{
+			{Source: "Federation", Type: "regroupe", Target: "Club_A"},
+			{Source: "Federation", Type: "regroupe", Target: "Club_B"},
+			{Source: "Federation", Type: "regroupe", Target: "Club_C"},
+		},
+	})
+
+	router.GET("/ontologies/:id/analytics", h.OntologyAnalytics)
+
+	req, _ := http.NewRequest("GET", "/ontologies/test1/analytics?top=2", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var analytics struct {
+		Nodes      int                      `json:"nodes"`
+		Centrality []map[string]interface{} `json:"centrality"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &analytics); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if analytics.Nodes != 4 || len(analytics.Centrality) != 2 {
+		t.Errorf("Expected 4 nodes and the top 2 ranked, got %+v", analytics)
+	}
+
+	req, _ = http.NewRequest("GET", "/ontologies/unknown/analytics", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown ontology, got %d", w.Code)
+	}
+}
+
+func TestRelationTypes(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID:        "test1",
+		Relations: []*models.Relation{{Source: "Club", Type: "contient", Target: "Equipe"}},
+	})
+
+	router.PUT("/ontologies/:id/relation-types", h.SetRelationTypes)
+	router.GET("/elements/relations/:element_name", h.GetElementRelations)
+
+	body := `[{"name": "est_partie_de", "inverse_of": "contient"}]`
+	req, _ := http.NewRequest("PUT", "/ontologies/test1/relation-types", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/elements/relations/Equipe", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var relations []models.Relation
+	if err := json.Unmarshal(w.Body.Bytes(), &relations); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(relations) != 2 || relations[1].Type != "est_partie_de" || relations[1].Inference == nil {
+		t.Errorf("Expected est_partie_de inferred from contient, got %+v", relations)
+	}
+
+	req, _ = http.NewRequest("PUT", "/ontologies/test1/relation-types", strings.NewReader(`[{"inverse_of": "contient"}]`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unnamed relation type, got %d", w.Code)
+	}
+}
+
+func TestRules(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID:        "test1",
+		Relations: []*models.Relation{{Source: "Cabinet", Type: "emploie", Target: "Avocat"}},
+	})
+
+	router.PUT("/ontologies/:id/rules", h.SetRules)
+	router.GET("/ontologies/:id/inferred-relations", h.GetInferredRelations)
+
+	body := `[{"name": "emploi", "rule": "A emploie B => B travaille_pour A"}]`
+	req, _ := http.NewRequest("PUT", "/ontologies/test1/rules", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/ontologies/test1/inferred-relations", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var relations []models.Relation
+	if err := json.Unmarshal(w.Body.Bytes(), &relations); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(relations) != 1 || relations[0].Source != "Avocat" || relations[0].Inference.Rule != "rule:emploi" {
+		t.Errorf("Expected Avocat travaille_pour Cabinet, got %+v", relations)
+	}
+
+	req, _ = http.NewRequest("PUT", "/ontologies/test1/rules", strings.NewReader(`[{"rule": "A emploie B => C travaille_pour A"}]`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unbound conclusion variable, got %d", w.Code)
+	}
+}
+
+func TestTypeTree(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID: "test1",
+		Elements: []*models.OntologyElement{
+			{Name: "Avocate", Type: "Avocat"},
+			{Name: "Conseil_Etat", Type: "Organisation"},
+		},
+	})
+
+	router.PUT("/ontologies/:id/types", h.SetTypeParents)
+	router.GET("/ontologies/:id/types", h.GetTypeTree)
+
+	req, _ := http.NewRequest("PUT", "/ontologies/test1/types", strings.NewReader(`{"Avocat": "Rôle"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var tree []storage.TypeNode
+	if err := json.Unmarshal(w.Body.Bytes(), &tree); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(tree) != 2 || tree[1].Name != "Rôle" || tree[1].Total != 1 || tree[1].Children[0].Name != "Avocat" {
+		t.Errorf("Expected Organisation and Rôle > Avocat, got %+v", tree)
+	}
+
+	req, _ = http.NewRequest("GET", "/ontologies/unknown/types", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown ontology, got %d", w.Code)
+	}
+}
+
+func TestPatternQuery(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID:        "test1",
+		Elements:  []*models.OntologyElement{{Name: "Club", Type: "Organisation"}, {Name: "Entraineur", Type: "Rôle"}},
+		Relations: []*models.Relation{{Source: "Club", Type: "emploie", Target: "Entraineur"}},
+	})
+
+	router.GET("/query", h.PatternQuery)
+	router.POST("/query", h.PatternQuery)
+
+	body := `{"query": "MATCH (o:Organisation)-[:emploie]->(r) RETURN o, r"}`
+	req, _ := http.NewRequest("POST", "/query", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result struct {
+		Rows [][]string `json:"rows"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0][1] != "Entraineur" {
+		t.Errorf("Expected Club and Entraineur, got %v", result.Rows)
+	}
+
+	req, _ = http.NewRequest("GET", "/query?q="+url.QueryEscape("MATCH (o RETURN o"), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a malformed query, got %d", w.Code)
+	}
+}
+
+func TestSPARQL(t *testing.T) {
+	h, router := setupTestHandler()
+
+	h.Storage.AddOntology(&models.Ontology{
+		ID:        "test1",
+		Elements:  []*models.OntologyElement{{Name: "Club", Type: "Organisation"}, {Name: "Entraineur", Type: "Rôle"}},
+		Relations: []*models.Relation{{Source: "Club", Type: "emploie", Target: "Entraineur"}},
+	})
+
+	router.GET("/sparql", h.SPARQL)
+	router.POST("/sparql", h.SPARQL)
+
+	query := `SELECT ?name WHERE { ?c rel:emploie ?e . ?e rdfs:label ?name }`
+	req, _ := http.NewRequest("GET", "/sparql?query="+url.QueryEscape(query), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != sparqlResultsContentType {
+		t.Errorf("Expected %s, got %s", sparqlResultsContentType, contentType)
+	}
+	var result struct {
+		Head struct {
+			Vars []string `json:"vars"`
+		} `json:"head"`
+		Results struct {
+			Bindings []map[string]map[string]string `json:"bindings"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(result.Head.Vars) != 1 || len(result.Results.Bindings) != 1 || result.Results.Bindings[0]["name"]["value"] != "Entraineur" {
+		t.Errorf("Unexpected SPARQL results %s", w.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/sparql", strings.NewReader(`CONSTRUCT { ?e rel:employe_par ?c } WHERE { ?c rel:emploie ?e }`))
+	req.Header.Set("Content-Type", sparqlQueryContentType)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/relation/employe_par>") {
+		t.Errorf("Expected N-Triples, got %d: %s", w.Code, w.Body.String())
+	}
+
+	form := url.Values{"query": {"ASK { ?s ?p ?o "}}
+	req, _ = http.NewRequest("POST", "/sparql", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a malformed query, got %d", w.Code)
+	}
+}
+
+func TestUnresolvedRelations(t *testing.T) {
+	h, router := setupTestHandler()
+
+	router.POST("/ontologies", h.AddOntology)
+	router.GET("/ontologies/:id/unresolved-relations", h.GetUnresolvedRelations)
+
+	// Les extrémités sont rattachées aussi pour une ontologie créée par l'API
+	body, _ := json.Marshal(models.Ontology{
+		Elements: []*models.OntologyElement{{Name: "Club"}, {Name: "Entraineur"}},
+		Relations: []*models.Relation{
+			{Source: "le Club", Type: "emploie", Target: "Entraineurs"},
+			{Source: "Club", Type: "affilié_à", Target: "Fédération"},
+		},
+	})
+	req, _ := http.NewRequest("POST", "/ontologies", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var created models.Ontology
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create ontology: %d %s", w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/ontologies/"+created.ID+"/unresolved-relations", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result struct {
+		Relations  int                          `json:"relations"`
+		Unresolved []*models.UnresolvedEndpoint `json:"unresolved"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if result.Relations != 2 || len(result.Unresolved) != 1 || result.Unresolved[0].Name != "Fédération" {
+		t.Errorf("Expected Fédération as the only unresolved endpoint, got %s", w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/ontologies/unknown/unresolved-relations", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestCreateSavedSearchPattern(t *testing.T) {
+	h, router := setupTestHandler()
+	manager, err := alerts.NewManager(h.Search, h.Logger, "", "")
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	h.Alerts = manager
+
+	router.POST("/saved-searches", h.CreateSavedSearch)
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"query": "Agent_(", "mode": "regex"}`, http.StatusBadRequest},
+		{`{"query": "` + strings.Repeat("a", 300) + `", "mode": "wildcard"}`, http.StatusBadRequest},
+		{`{"query": "Agent_.*", "mode": "regex"}`, http.StatusCreated},
+		{`{"query": "*_Juridique", "mode": "wildcard"}`, http.StatusCreated},
+		{`{"query": "Agent_(", "mode": "lexical"}`, http.StatusCreated},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("POST", "/saved-searches", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.want {
+			t.Errorf("%s: expected status %d, got %d (%s)", test.body, test.want, w.Code, w.Body.String())
+		}
+	}
+}
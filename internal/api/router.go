@@ -1,16 +1,49 @@
 package api
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/chrlesur/ontology-server/internal/alerts"
+	"github.com/chrlesur/ontology-server/internal/config"
 	"github.com/chrlesur/ontology-server/internal/logger"
+	"github.com/chrlesur/ontology-server/internal/parser"
 	"github.com/chrlesur/ontology-server/internal/search"
 	"github.com/chrlesur/ontology-server/internal/storage"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.RouterGroup, storage *storage.MemoryStorage, logger *logger.Logger) {
+func SetupRoutes(router *gin.RouterGroup, storage *storage.MemoryStorage, logger *logger.Logger, cfg *config.Config) error {
 	searchEngine := search.NewSearchEngine(storage, logger)
 	handler := NewHandler(storage, logger, searchEngine)
 
+	if cfg.Search.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Search.Timeout)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Invalid search timeout %s: %v", cfg.Search.Timeout, err))
+		} else {
+			searchEngine.Timeout = timeout
+		}
+	}
+
+	searchEngine.Cache.Resize(cfg.Search.CacheEntries, cfg.Search.CacheBytes)
+
+	if cfg.Search.SynonymsFile != "" {
+		sets, err := parser.ParseSynonymsTSV(cfg.Search.SynonymsFile)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to load synonyms file %s: %v", cfg.Search.SynonymsFile, err))
+		} else {
+			logger.Info(fmt.Sprintf("Loaded %d global synonym sets", searchEngine.Synonyms.Load("", sets)))
+		}
+	}
+
+	manager, err := alerts.NewManager(searchEngine, logger, cfg.Alerts.File, cfg.Alerts.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("failed to load saved searches from %s: %w", cfg.Alerts.File, err)
+	}
+	handler.Alerts = manager
+	storage.OnOntologyLoaded(manager.Evaluate)
+
 	router.GET("/ontologies", handler.ListOntologies)
 	router.POST("/ontologies", handler.AddOntology)
 	router.GET("/ontologies/:id", handler.GetOntology)
@@ -19,12 +52,49 @@ func SetupRoutes(router *gin.RouterGroup, storage *storage.MemoryStorage, logger
 	router.POST("/ontologies/load", handler.LoadOntology)
 	router.GET("/ontologies/files", handler.GetOntologyFiles)
 	router.GET("/ontologies/:id/metadata", handler.GetOntologyMetadata)
+	router.GET("/ontologies/:id/analytics", handler.OntologyAnalytics)
+	router.GET("/ontologies/:id/relation-types", handler.GetRelationTypes)
+	router.PUT("/ontologies/:id/relation-types", handler.SetRelationTypes)
+	router.GET("/ontologies/:id/types", handler.GetTypeTree)
+	router.PUT("/ontologies/:id/types", handler.SetTypeParents)
+	router.GET("/ontologies/:id/rules", handler.GetRules)
+	router.PUT("/ontologies/:id/rules", handler.SetRules)
+	router.GET("/ontologies/:id/inferred-relations", handler.GetInferredRelations)
+	router.GET("/ontologies/:id/unresolved-relations", handler.GetUnresolvedRelations)
+	router.PUT("/ontologies/:id/elements/:element_name/labels", handler.SetElementAltLabels)
 
 	router.GET("/search", handler.SearchOntologies)
+	router.GET("/search/facets", handler.SearchFacets)
+	router.GET("/search/cache", handler.SearchCacheStats)
+	router.DELETE("/search/cache", handler.ClearSearchCache)
+	router.GET("/suggest", handler.Suggest)
+
+	router.GET("/synonyms", handler.ListSynonyms)
+	router.POST("/synonyms", handler.AddSynonyms)
+	router.POST("/synonyms/load", handler.LoadSynonyms)
+	router.DELETE("/synonyms", handler.ClearSynonyms)
+
+	router.GET("/saved-searches", handler.ListSavedSearches)
+	router.POST("/saved-searches", handler.CreateSavedSearch)
+	router.GET("/saved-searches/:id", handler.GetSavedSearch)
+	router.DELETE("/saved-searches/:id", handler.DeleteSavedSearch)
+	router.GET("/saved-searches/:id/inbox", handler.GetSavedSearchInbox)
+	router.DELETE("/saved-searches/:id/inbox", handler.ClearSavedSearchInbox)
 
 	router.GET("/elements/details/:element_id", handler.ElementDetailsHandler)
 	router.GET("/elements/relations/:element_name", handler.GetElementRelations)
+	router.GET("/elements/:id/similar", handler.SimilarElements)
+	router.GET("/elements/:id/neighbourhood", handler.Neighbourhood)
+
+	router.GET("/paths", handler.Paths)
+	router.GET("/query", handler.PatternQuery)
+	router.POST("/query", handler.PatternQuery)
+	router.GET("/sparql", handler.SPARQL)
+	router.POST("/sparql", handler.SPARQL)
+
+	router.GET("/files/:fileId/elements", handler.GetFileElements)
 
 	router.GET("/view-source", handler.ViewSourceFile)
 
+	return nil
 }
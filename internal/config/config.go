@@ -21,6 +21,16 @@ type Config struct {
 	Storage struct {
 		TempDirectory string `yaml:"temp_directory"`
 	} `yaml:"storage"`
+	Search struct {
+		SynonymsFile string `yaml:"synonyms_file"` // Synonymes globaux chargés au démarrage (TSV)
+		Timeout      string `yaml:"timeout"`       // Durée maximale d'une recherche (ex. "10s"), vide pour aucune limite
+		CacheEntries int    `yaml:"cache_entries"` // Nombre maximal de réponses en cache (0 : valeur par défaut)
+		CacheBytes   int    `yaml:"cache_bytes"`   // Taille maximale du cache en octets (0 : valeur par défaut)
+	} `yaml:"search"`
+	Alerts struct {
+		File       string `yaml:"file"`        // Fichier JSON de persistance des recherches enregistrées
+		WebhookURL string `yaml:"webhook_url"` // URL recevant les nouvelles correspondances, vide pour désactiver
+	} `yaml:"alerts"`
 }
 
 // LoadConfig reads the config file and returns a Config struct
@@ -0,0 +1,435 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Paramètres des calculs d'analyse du graphe
+const (
+	// pageRankDamping est le facteur d'amortissement du PageRank
+	pageRankDamping = 0.85
+	// pageRankIterations borne le nombre d'itérations du PageRank
+	pageRankIterations = 100
+	// pageRankTolerance arrête le PageRank lorsque la variation totale devient inférieure
+	pageRankTolerance = 1e-9
+	// louvainPasses borne le nombre de passes de déplacement local de l'algorithme de Louvain
+	louvainPasses = 100
+)
+
+// NodeCentrality contient les mesures de centralité d'un nœud
+type NodeCentrality struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Degree    int    `json:"degree"`
+	InDegree  int    `json:"in_degree"`
+	OutDegree int    `json:"out_degree"`
+	// Betweenness est la centralité d'intermédiarité normalisée sur le graphe orienté
+	Betweenness float64 `json:"betweenness"`
+	PageRank    float64 `json:"pagerank"`
+	// Component et Community sont les indices de la composante connexe et de la communauté du nœud
+	Component int `json:"component"`
+	Community int `json:"community"`
+}
+
+// Group est un ensemble de nœuds : composante faiblement connexe ou communauté
+type Group struct {
+	ID      int      `json:"id"`
+	Size    int      `json:"size"`
+	Members []string `json:"members"`
+}
+
+// Analytics contient les mesures calculées sur le graphe d'une ontologie
+type Analytics struct {
+	OntologyID string           `json:"ontology_id"`
+	Nodes      int              `json:"nodes"`
+	Edges      int              `json:"edges"`
+	Centrality []NodeCentrality `json:"centrality"`
+	// Components sont les composantes faiblement connexes, de la plus grande à la plus petite
+	Components []Group `json:"components"`
+	// Communities sont les communautés détectées par l'algorithme de Louvain, de la plus grande à la plus petite
+	Communities []Group `json:"communities"`
+	Modularity  float64 `json:"modularity"`
+}
+
+// Analytics retourne les mesures du graphe d'une ontologie. Elles sont calculées au premier
+// appel puis conservées jusqu'à la prochaine modification de l'ontologie.
+func (g *Graph) Analytics(ontologyID string) (*Analytics, error) {
+	graphs := g.graphs([]string{ontologyID})
+	if len(graphs) == 0 {
+		return nil, fmt.Errorf("ontology %s not found", ontologyID)
+	}
+	og := graphs[0]
+	og.analyticsOnce.Do(func() {
+		og.analytics = og.computeAnalytics()
+	})
+	return og.analytics, nil
+}
+
+// nodeIDs retourne les identifiants des nœuds de l'ontologie, triés.
+// La liste est partagée entre les appelants et ne doit pas être modifiée.
+func (og *ontologyGraph) nodeIDs() []string {
+	og.idsOnce.Do(func() {
+		og.ids = make([]string, 0, len(og.labels))
+		for id := range og.labels {
+			og.ids = append(og.ids, id)
+		}
+		sort.Strings(og.ids)
+	})
+	return og.ids
+}
+
+// computeAnalytics calcule les centralités, composantes et communautés du graphe
+func (og *ontologyGraph) computeAnalytics() *Analytics {
+	ids := og.nodeIDs()
+	index := make(map[string]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+
+	// Voisinages orienté (sans doublon) et non orienté (sans relation réflexive)
+	successors := make([][]int, len(ids))
+	neighbours := make([]map[int]bool, len(ids))
+	for i := range neighbours {
+		neighbours[i] = make(map[int]bool)
+	}
+	centrality := make([]NodeCentrality, len(ids))
+	for i, id := range ids {
+		centrality[i] = NodeCentrality{ID: id, Name: og.labels[id]}
+	}
+	edges := 0
+	for i, id := range ids {
+		seen := make(map[int]bool)
+		for _, edge := range og.outgoing.edges(id, nil) {
+			// Les mesures portent sur les relations déclarées uniquement
+			if edge.Inferred() {
+				continue
+			}
+			edges++
+			j := index[edge.Target]
+			centrality[i].OutDegree++
+			centrality[j].InDegree++
+			if !seen[j] {
+				seen[j] = true
+				successors[i] = append(successors[i], j)
+			}
+			if i != j {
+				neighbours[i][j] = true
+				neighbours[j][i] = true
+			}
+		}
+	}
+	for i := range centrality {
+		centrality[i].Degree = centrality[i].InDegree + centrality[i].OutDegree
+	}
+
+	for i, value := range betweenness(successors) {
+		centrality[i].Betweenness = value
+	}
+	for i, value := range pageRank(successors) {
+		centrality[i].PageRank = value
+	}
+
+	components := groups(ids, weakComponents(neighbours))
+	labels := louvain(neighbours)
+	communities := groups(ids, labels)
+	// Numéroter les nœuds puis remplacer leurs identifiants par leurs noms affichés
+	for _, group := range components {
+		for i, member := range group.Members {
+			centrality[index[member]].Component = group.ID
+			group.Members[i] = og.labels[member]
+		}
+	}
+	for _, group := range communities {
+		for i, member := range group.Members {
+			centrality[index[member]].Community = group.ID
+			group.Members[i] = og.labels[member]
+		}
+	}
+
+	sort.SliceStable(centrality, func(i, j int) bool {
+		if centrality[i].PageRank != centrality[j].PageRank {
+			return centrality[i].PageRank > centrality[j].PageRank
+		}
+		return centrality[i].Degree > centrality[j].Degree
+	})
+
+	return &Analytics{
+		OntologyID:  og.ontology.ID,
+		Nodes:       len(ids),
+		Edges:       edges,
+		Centrality:  centrality,
+		Components:  components,
+		Communities: communities,
+		Modularity:  modularity(neighbours, labels),
+	}
+}
+
+// betweenness calcule la centralité d'intermédiarité de chaque nœud par l'algorithme de Brandes,
+// normalisée par (n-1)(n-2)
+func betweenness(successors [][]int) []float64 {
+	n := len(successors)
+	scores := make([]float64, n)
+	for s := 0; s < n; s++ {
+		var stack []int
+		predecessors := make([][]int, n)
+		paths := make([]float64, n)
+		distance := make([]int, n)
+		for i := range distance {
+			distance[i] = -1
+		}
+		paths[s], distance[s] = 1, 0
+
+		queue := []int{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+			for _, w := range successors[v] {
+				if distance[w] < 0 {
+					distance[w] = distance[v] + 1
+					queue = append(queue, w)
+				}
+				if distance[w] == distance[v]+1 {
+					paths[w] += paths[v]
+					predecessors[w] = append(predecessors[w], v)
+				}
+			}
+		}
+
+		dependency := make([]float64, n)
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range predecessors[w] {
+				dependency[v] += paths[v] / paths[w] * (1 + dependency[w])
+			}
+			if w != s {
+				scores[w] += dependency[w]
+			}
+		}
+	}
+	if n > 2 {
+		for i := range scores {
+			scores[i] /= float64((n - 1) * (n - 2))
+		}
+	}
+	return scores
+}
+
+// pageRank calcule le PageRank de chaque nœud ; le rang des nœuds sans successeur est réparti uniformément
+func pageRank(successors [][]int) []float64 {
+	n := len(successors)
+	if n == 0 {
+		return nil
+	}
+	ranks := make([]float64, n)
+	for i := range ranks {
+		ranks[i] = 1 / float64(n)
+	}
+	for iteration := 0; iteration < pageRankIterations; iteration++ {
+		dangling := 0.0
+		next := make([]float64, n)
+		for v, targets := range successors {
+			if len(targets) == 0 {
+				dangling += ranks[v]
+				continue
+			}
+			share := ranks[v] / float64(len(targets))
+			for _, w := range targets {
+				next[w] += share
+			}
+		}
+		delta := 0.0
+		for i := range next {
+			next[i] = (1-pageRankDamping)/float64(n) + pageRankDamping*(next[i]+dangling/float64(n))
+			delta += math.Abs(next[i] - ranks[i])
+		}
+		ranks = next
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+	return ranks
+}
+
+// weakComponents attribue à chaque nœud le plus petit indice de sa composante faiblement connexe
+func weakComponents(neighbours []map[int]bool) []int {
+	component := make([]int, len(neighbours))
+	for i := range component {
+		component[i] = -1
+	}
+	for start := range neighbours {
+		if component[start] >= 0 {
+			continue
+		}
+		component[start] = start
+		queue := []int{start}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			for w := range neighbours[v] {
+				if component[w] < 0 {
+					component[w] = start
+					queue = append(queue, w)
+				}
+			}
+		}
+	}
+	return component
+}
+
+// louvain détecte les communautés en maximisant la modularité : chaque nœud rejoint, dans l'ordre
+// des indices, la communauté voisine qui augmente le plus la modularité, puis les communautés
+// sont agrégées en nœuds et l'opération est répétée tant qu'elle regroupe des nœuds
+func louvain(neighbours []map[int]bool) []int {
+	n := len(neighbours)
+	// weights est la matrice d'adjacence pondérée et symétrique du niveau courant
+	weights := make([]map[int]float64, n)
+	for v, adjacent := range neighbours {
+		weights[v] = make(map[int]float64, len(adjacent))
+		for w := range adjacent {
+			weights[v][w] = 1
+		}
+	}
+	// membership associe chaque nœud d'origine à son nœud du niveau courant
+	membership := make([]int, n)
+	for i := range membership {
+		membership[i] = i
+	}
+
+	for {
+		communities, moved := louvainLevel(weights)
+		if !moved {
+			break
+		}
+		// Renuméroter les communautés puis les agréger en nœuds du niveau suivant
+		renumber := make(map[int]int)
+		for _, c := range communities {
+			if _, exists := renumber[c]; !exists {
+				renumber[c] = len(renumber)
+			}
+		}
+		aggregated := make([]map[int]float64, len(renumber))
+		for i := range aggregated {
+			aggregated[i] = make(map[int]float64)
+		}
+		for v, adjacent := range weights {
+			for w, weight := range adjacent {
+				aggregated[renumber[communities[v]]][renumber[communities[w]]] += weight
+			}
+		}
+		for i, node := range membership {
+			membership[i] = renumber[communities[node]]
+		}
+		weights = aggregated
+	}
+	return membership
+}
+
+// louvainLevel déplace les nœuds d'un niveau entre communautés tant que la modularité augmente.
+// Le second résultat indique qu'au moins un nœud a changé de communauté.
+func louvainLevel(weights []map[int]float64) ([]int, bool) {
+	n := len(weights)
+	community := make([]int, n)
+	degree := make([]float64, n)
+	total := make(map[int]float64)
+	twiceEdges := 0.0
+	for v, adjacent := range weights {
+		community[v] = v
+		for _, weight := range adjacent {
+			degree[v] += weight
+		}
+		total[v] = degree[v]
+		twiceEdges += degree[v]
+	}
+	if twiceEdges == 0 {
+		return community, false
+	}
+
+	moved := false
+	for pass := 0; pass < louvainPasses; pass++ {
+		changed := false
+		for v := 0; v < n; v++ {
+			current := community[v]
+			total[current] -= degree[v]
+
+			// Poids des liens de v vers chaque communauté voisine
+			links := make(map[int]float64)
+			for w, weight := range weights[v] {
+				if w != v {
+					links[community[w]] += weight
+				}
+			}
+			best := current
+			bestGain := links[current] - total[current]*degree[v]/twiceEdges
+			candidates := make([]int, 0, len(links))
+			for c := range links {
+				candidates = append(candidates, c)
+			}
+			sort.Ints(candidates)
+			for _, c := range candidates {
+				if gain := links[c] - total[c]*degree[v]/twiceEdges; gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+
+			community[v] = best
+			total[best] += degree[v]
+			if best != current {
+				changed, moved = true, true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return community, moved
+}
+
+// modularity mesure la qualité d'un partitionnement du graphe non orienté
+func modularity(neighbours []map[int]bool, labels []int) float64 {
+	twiceEdges := 0.0
+	for _, adjacent := range neighbours {
+		twiceEdges += float64(len(adjacent))
+	}
+	if twiceEdges == 0 {
+		return 0
+	}
+	// Q = somme sur les communautés de (liens internes / m) - (degré total / 2m)²
+	internal := make(map[int]float64)
+	degrees := make(map[int]float64)
+	for v, adjacent := range neighbours {
+		degrees[labels[v]] += float64(len(adjacent))
+		for w := range adjacent {
+			if labels[w] == labels[v] {
+				internal[labels[v]]++
+			}
+		}
+	}
+	q := 0.0
+	for label, degree := range degrees {
+		q += internal[label]/twiceEdges - math.Pow(degree/twiceEdges, 2)
+	}
+	return q
+}
+
+// groups regroupe les nœuds par étiquette, numérotés du plus grand groupe au plus petit
+func groups(ids []string, labels []int) []Group {
+	members := make(map[int][]string)
+	var order []int
+	for i, label := range labels {
+		if _, exists := members[label]; !exists {
+			order = append(order, label)
+		}
+		members[label] = append(members[label], ids[i])
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(members[order[i]]) > len(members[order[j]])
+	})
+	result := make([]Group, 0, len(order))
+	for i, label := range order {
+		result = append(result, Group{ID: i, Size: len(members[label]), Members: members[label]})
+	}
+	return result
+}
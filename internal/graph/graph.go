@@ -0,0 +1,290 @@
+package graph
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+	"github.com/chrlesur/ontology-server/internal/storage"
+)
+
+// Sens de parcours des relations
+const (
+	DirectionOutgoing = "outgoing"
+	DirectionIncoming = "incoming"
+	DirectionBoth     = "both"
+)
+
+// Edge est une relation orientée entre deux éléments d'une ontologie
+type Edge struct {
+	OntologyID string
+	// Source et Target sont les identifiants canoniques des extrémités
+	Source   string
+	Target   string
+	Type     string
+	Relation *models.Relation
+	// index est la position de la relation dans l'ontologie, pour restituer l'ordre d'origine
+	index int
+}
+
+// adjacency associe à chaque nœud ses arêtes, regroupées par type de relation
+type adjacency map[string]map[string][]*Edge
+
+func (a adjacency) add(node string, edge *Edge) {
+	if a[node] == nil {
+		a[node] = make(map[string][]*Edge)
+	}
+	a[node][edge.Type] = append(a[node][edge.Type], edge)
+}
+
+// edges retourne les arêtes d'un nœud, limitées aux types indiqués s'il y en a
+func (a adjacency) edges(node string, types []string) []*Edge {
+	byType := a[node]
+	if len(types) == 0 {
+		var edges []*Edge
+		for _, typed := range byType {
+			edges = append(edges, typed...)
+		}
+		return edges
+	}
+	var edges []*Edge
+	for _, t := range types {
+		edges = append(edges, byType[t]...)
+	}
+	return edges
+}
+
+// ontologyGraph contient les listes d'adjacence d'une version d'ontologie
+type ontologyGraph struct {
+	ontology *models.Ontology
+	outgoing adjacency
+	incoming adjacency
+	// byType regroupe toutes les arêtes par type de relation
+	byType map[string][]*Edge
+	// labels associe à chaque identifiant canonique le nom affiché du nœud
+	labels map[string]string
+	// elements associe à chaque identifiant canonique l'élément déclaré, absent pour une extrémité non déclarée
+	elements map[string]*models.OntologyElement
+	// analytics conserve les mesures du graphe, calculées au plus une fois par version de l'ontologie
+	analytics     *Analytics
+	analyticsOnce sync.Once
+	// ids conserve les identifiants triés des nœuds, calculés au plus une fois par version de l'ontologie
+	ids     []string
+	idsOnce sync.Once
+}
+
+// CanonicalID retourne l'identifiant canonique d'un élément ou d'une extrémité de relation
+func CanonicalID(name string) string {
+	return storage.NormalizeElementName(name)
+}
+
+// buildOntologyGraph construit les listes d'adjacence d'une ontologie
+func buildOntologyGraph(onto *models.Ontology) *ontologyGraph {
+	og := &ontologyGraph{
+		ontology: onto,
+		outgoing: make(adjacency),
+		incoming: make(adjacency),
+		byType:   make(map[string][]*Edge),
+		labels:   make(map[string]string),
+		elements: make(map[string]*models.OntologyElement),
+	}
+	for _, element := range onto.Elements {
+		id := CanonicalID(element.Name)
+		og.labels[id] = element.Name
+		og.elements[id] = element
+	}
+	for i, relation := range onto.Relations {
+		source, target := storage.RelationEndpoints(relation)
+		edge := &Edge{
+			OntologyID: onto.ID,
+			Source:     CanonicalID(source),
+			Target:     CanonicalID(target),
+			Type:       relation.Type,
+			Relation:   relation,
+			index:      i,
+		}
+		og.addEdge(edge)
+		for id, name := range map[string]string{edge.Source: source, edge.Target: target} {
+			if _, exists := og.labels[id]; !exists {
+				og.labels[id] = name
+			}
+		}
+	}
+	og.inferRelations()
+	return og
+}
+
+// Graph indexe les relations de toutes les ontologies stockées sous forme de listes d'adjacence.
+// Il est tenu à jour à chaque ajout, modification ou suppression d'ontologie.
+type Graph struct {
+	mutex      sync.RWMutex
+	storage    *storage.MemoryStorage
+	ontologies map[string]*ontologyGraph
+}
+
+// New construit l'index des relations des ontologies stockées et s'abonne à leurs modifications
+func New(ms *storage.MemoryStorage) *Graph {
+	g := &Graph{
+		storage:    ms,
+		ontologies: make(map[string]*ontologyGraph),
+	}
+	for _, onto := range ms.ListOntologies() {
+		g.ontologies[onto.ID] = buildOntologyGraph(onto)
+	}
+	ms.OnChange(g.refresh)
+	return g
+}
+
+// refresh reconstruit, ou retire, les listes d'adjacence d'une ontologie modifiée.
+// Une mise à jour concurrente peut remplacer l'ontologie pendant la construction : le graphe n'est
+// installé que s'il correspond encore à la version stockée, sinon il est reconstruit, pour qu'un
+// rafraîchissement plus lent n'écrase pas celui d'une version plus récente.
+func (g *Graph) refresh(ontologyID string) {
+	for {
+		onto, _ := g.storage.GetOntology(ontologyID)
+		var og *ontologyGraph
+		if onto != nil {
+			og = buildOntologyGraph(onto)
+		}
+
+		g.mutex.Lock()
+		current, _ := g.storage.GetOntology(ontologyID)
+		if current == onto {
+			if og == nil {
+				delete(g.ontologies, ontologyID)
+			} else {
+				g.ontologies[ontologyID] = og
+			}
+			g.mutex.Unlock()
+			return
+		}
+		g.mutex.Unlock()
+	}
+}
+
+// graphs retourne les graphes des ontologies indiquées, ou de toutes si la liste est vide, triés par identifiant
+func (g *Graph) graphs(ontologyIDs []string) []*ontologyGraph {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	var graphs []*ontologyGraph
+	if len(ontologyIDs) == 0 {
+		for _, og := range g.ontologies {
+			graphs = append(graphs, og)
+		}
+	} else {
+		for _, id := range ontologyIDs {
+			if og, exists := g.ontologies[id]; exists {
+				graphs = append(graphs, og)
+			}
+		}
+	}
+	sort.Slice(graphs, func(i, j int) bool {
+		return graphs[i].ontology.ID < graphs[j].ontology.ID
+	})
+	return graphs
+}
+
+// Edges retourne les arêtes d'un nœud dans une ontologie, selon le sens et les types de relation demandés
+func (g *Graph) Edges(ontologyID, node, direction string, types []string) []*Edge {
+	graphs := g.graphs([]string{ontologyID})
+	if len(graphs) == 0 {
+		return nil
+	}
+	return graphs[0].edges(node, direction, types)
+}
+
+// edges retourne les arêtes d'un nœud selon le sens demandé, dans l'ordre des relations de l'ontologie
+func (og *ontologyGraph) edges(node, direction string, types []string) []*Edge {
+	var edges []*Edge
+	if direction != DirectionIncoming {
+		edges = append(edges, og.outgoing.edges(node, types)...)
+	}
+	if direction != DirectionOutgoing {
+		for _, edge := range og.incoming.edges(node, types) {
+			// Une relation réflexive figure déjà parmi les arêtes sortantes
+			if direction == DirectionBoth && edge.Source == node {
+				continue
+			}
+			edges = append(edges, edge)
+		}
+	}
+	sortEdges(edges)
+	return edges
+}
+
+// addEdge ajoute une arête aux index du graphe
+func (og *ontologyGraph) addEdge(edge *Edge) {
+	og.outgoing.add(edge.Source, edge)
+	og.incoming.add(edge.Target, edge)
+	og.byType[edge.Type] = append(og.byType[edge.Type], edge)
+}
+
+// sortEdges trie les arêtes dans l'ordre des relations de l'ontologie, les relations déduites en dernier
+func sortEdges(edges []*Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].index < edges[j].index
+	})
+}
+
+// Relations retourne les relations dont l'élément est la source ou la cible, dans toutes les ontologies,
+// y compris celles déduites des propriétés des types de relation
+func (g *Graph) Relations(elementName string) []*models.Relation {
+	node := CanonicalID(elementName)
+	var relations []*models.Relation
+	for _, og := range g.graphs(nil) {
+		for _, edge := range og.edges(node, DirectionBoth, nil) {
+			relations = append(relations, edge.Relation)
+		}
+	}
+	return relations
+}
+
+// NeighbourSets retourne, pour chaque nœud, les identifiants canoniques des nœuds qui lui sont reliés
+// par une relation déclarée, toutes ontologies confondues
+func (g *Graph) NeighbourSets() map[string]map[string]bool {
+	neighbours := make(map[string]map[string]bool)
+	link := func(from, to string) {
+		if neighbours[from] == nil {
+			neighbours[from] = make(map[string]bool)
+		}
+		neighbours[from][to] = true
+	}
+	for _, og := range g.graphs(nil) {
+		for _, edges := range og.byType {
+			for _, edge := range edges {
+				if edge.Inferred() || edge.Source == edge.Target {
+					continue
+				}
+				link(edge.Source, edge.Target)
+				link(edge.Target, edge.Source)
+			}
+		}
+	}
+	return neighbours
+}
+
+// OntologyEdges retourne toutes les arêtes d'une ontologie, déclarées puis déduites, dans leur ordre d'origine
+func (g *Graph) OntologyEdges(ontologyID string) []*Edge {
+	graphs := g.graphs([]string{ontologyID})
+	if len(graphs) == 0 {
+		return nil
+	}
+	var edges []*Edge
+	for _, typed := range graphs[0].byType {
+		edges = append(edges, typed...)
+	}
+	sortEdges(edges)
+	return edges
+}
+
+// Label retourne le nom affiché d'un nœud d'une ontologie
+func (g *Graph) Label(ontologyID, node string) string {
+	graphs := g.graphs([]string{ontologyID})
+	if len(graphs) > 0 {
+		if label, exists := graphs[0].labels[node]; exists {
+			return label
+		}
+	}
+	return node
+}
@@ -0,0 +1,487 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+	"github.com/chrlesur/ontology-server/internal/storage"
+)
+
+func TestGraphAdjacency(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID:       "onto1",
+		Elements: []*models.OntologyElement{{Name: "Conseil_Etat"}, {Name: "Juridiction"}},
+		Relations: []*models.Relation{
+			{Source: "Conseil_Etat", Type: "est_un", Target: "Juridiction"},
+			{Source: "Conseil_Etat", Type: "conseille", Target: "Gouvernement"},
+			{Source: "Tribunal", Type: "est_un", Target: "Juridiction"},
+		},
+	})
+	g := New(ms)
+
+	relations := g.Relations("Conseil Etat")
+	if len(relations) != 2 || relations[0].Target != "Juridiction" || relations[1].Target != "Gouvernement" {
+		t.Fatalf("Expected the two relations of Conseil_Etat in order, got %+v", relations)
+	}
+
+	incoming := g.Edges("onto1", CanonicalID("Juridiction"), DirectionIncoming, []string{"est_un"})
+	if len(incoming) != 2 {
+		t.Errorf("Expected 2 incoming est_un edges, got %d", len(incoming))
+	}
+	if outgoing := g.Edges("onto1", CanonicalID("Conseil_Etat"), DirectionOutgoing, []string{"conseille"}); len(outgoing) != 1 {
+		t.Errorf("Expected 1 outgoing conseille edge, got %d", len(outgoing))
+	}
+	if label := g.Label("onto1", CanonicalID("Gouvernement")); label != "Gouvernement" {
+		t.Errorf("Expected endpoint label Gouvernement, got %s", label)
+	}
+
+	// L'index suit les modifications et suppressions d'ontologies
+	ms.UpdateOntology(&models.Ontology{
+		ID:        "onto1",
+		Relations: []*models.Relation{{Source: "Conseil_Etat", Type: "siège_à", Target: "Palais_Royal"}},
+	})
+	if relations = g.Relations("Conseil_Etat"); len(relations) != 1 || relations[0].Type != "siège_à" {
+		t.Errorf("Expected the updated relation, got %+v", relations)
+	}
+	ms.DeleteOntology("onto1")
+	if relations = g.Relations("Conseil_Etat"); len(relations) != 0 {
+		t.Errorf("Expected no relation after deletion, got %+v", relations)
+	}
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{ID: "onto1"})
+	g := New(ms)
+
+	// Quel que soit l'ordre de fin des rafraîchissements, le graphe suit la dernière version stockée
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			onto := &models.Ontology{ID: "onto1"}
+			for j := 0; j < n; j++ {
+				onto.Relations = append(onto.Relations, &models.Relation{Source: "A", Type: "lie", Target: fmt.Sprintf("N%d", j)})
+			}
+			ms.UpdateOntology(onto)
+		}(i)
+	}
+	wg.Wait()
+
+	stored, _ := ms.GetOntology("onto1")
+	if edges := g.OntologyEdges("onto1"); len(edges) != len(stored.Relations) {
+		t.Errorf("Expected the graph of the stored version with %d relations, got %d edges", len(stored.Relations), len(edges))
+	}
+}
+
+func TestNeighbourhood(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID:       "onto1",
+		Elements: []*models.OntologyElement{{Name: "A", Type: "Concept"}, {Name: "B"}, {Name: "C"}, {Name: "D"}},
+		Relations: []*models.Relation{
+			{Source: "A", Type: "lie", Target: "B"},
+			{Source: "B", Type: "lie", Target: "C"},
+			{Source: "D", Type: "emploie", Target: "A"},
+			{Source: "C", Type: "lie", Target: "E"},
+		},
+	})
+	g := New(ms)
+
+	subgraph, err := g.Neighbourhood("A", NeighbourhoodOptions{Depth: 2})
+	if err != nil {
+		t.Fatalf("Neighbourhood failed: %v", err)
+	}
+	if len(subgraph.Nodes) != 4 || len(subgraph.Edges) != 3 || subgraph.Truncated {
+		t.Fatalf("Expected A, B, D and C within two hops, got %+v", subgraph)
+	}
+	if subgraph.Nodes[0].Name != "A" || subgraph.Nodes[0].Type != "Concept" || subgraph.Nodes[3].Depth != 2 {
+		t.Errorf("Unexpected nodes: %+v", subgraph.Nodes)
+	}
+
+	subgraph, _ = g.Neighbourhood("A", NeighbourhoodOptions{Depth: 5, Direction: DirectionOutgoing, Types: []string{"lie"}})
+	if len(subgraph.Nodes) != 4 || subgraph.Nodes[3].Name != "E" {
+		t.Errorf("Expected the outgoing lie chain up to E, got %+v", subgraph.Nodes)
+	}
+
+	subgraph, _ = g.Neighbourhood("A", NeighbourhoodOptions{Depth: 5, MaxNodes: 2})
+	if len(subgraph.Nodes) != 2 || !subgraph.Truncated {
+		t.Errorf("Expected a truncated neighbourhood of 2 nodes, got %+v", subgraph)
+	}
+
+	if _, err := g.Neighbourhood("Inconnu", NeighbourhoodOptions{}); err == nil {
+		t.Error("Expected error for an unknown element, got nil")
+	}
+}
+
+func TestPaths(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID:       "onto1",
+		Elements: []*models.OntologyElement{{Name: "Joueuse_Internationale"}, {Name: "Principe_De_Neutralite"}},
+		Relations: []*models.Relation{
+			{Source: "Joueuse_Internationale", Type: "membre_de", Target: "Federation", Description: "licence"},
+			{Source: "Federation", Type: "respecte", Target: "Principe_De_Neutralite"},
+			{Source: "Joueuse_Internationale", Type: "joue_pour", Target: "Equipe"},
+			{Source: "Equipe", Type: "affiliee_a", Target: "Ligue"},
+			{Source: "Principe_De_Neutralite", Type: "impose_a", Target: "Ligue"},
+			{Source: "Joueuse_Internationale", Type: "joue_pour", Target: "Federation"},
+		},
+	})
+	g := New(ms)
+
+	result, err := g.Paths("Joueuse Internationale", "Principe_De_Neutralite", PathOptions{})
+	if err != nil {
+		t.Fatalf("Paths failed: %v", err)
+	}
+	if len(result.Paths) != 1 || result.Paths[0].Length != 2 || result.Paths[0].Relations[0].Description != "licence" {
+		t.Fatalf("Expected the two-hop path through Federation, got %+v", result.Paths)
+	}
+
+	result, _ = g.Paths("Joueuse_Internationale", "Principe_De_Neutralite", PathOptions{Mode: PathModeKShortest, K: 5})
+	if len(result.Paths) != 3 || result.Paths[2].Length != 3 || !result.Paths[2].Relations[2].Inverse {
+		t.Fatalf("Expected two direct paths and one inverse path through Ligue, got %+v", result.Paths)
+	}
+
+	result, _ = g.Paths("Joueuse_Internationale", "Principe_De_Neutralite", PathOptions{Mode: PathModeAllSimple, Types: []string{"joue_pour", "respecte"}})
+	if len(result.Paths) != 1 || result.Paths[0].Relations[0].Type != "joue_pour" {
+		t.Errorf("Expected a single path restricted to joue_pour and respecte, got %+v", result.Paths)
+	}
+
+	result, _ = g.Paths("Joueuse_Internationale", "Principe_De_Neutralite", PathOptions{Mode: PathModeAllSimple, Direction: DirectionOutgoing})
+	if len(result.Paths) != 2 {
+		t.Errorf("Expected two outgoing paths, got %+v", result.Paths)
+	}
+
+	if _, err := g.Paths("Joueuse_Internationale", "Inconnu", PathOptions{}); err == nil {
+		t.Error("Expected error for an unknown element, got nil")
+	}
+}
+
+func TestSimplePathsLimits(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	// Les copies déduites d'une relation symétrique ou inverse ne produisent pas de chemins en double
+	ms.AddOntology(&models.Ontology{
+		ID: "onto1",
+		Relations: []*models.Relation{
+			{Source: "A", Type: "voisin_de", Target: "B"},
+			{Source: "B", Type: "contient", Target: "C"},
+		},
+		RelationTypes: []*models.RelationType{
+			{Name: "voisin_de", Symmetric: true},
+			{Name: "contient", InverseOf: "partie_de"},
+		},
+	})
+	// Une clique de 12 nœuds sans chemin vers Isole épuise le budget d'exploration
+	clique := &models.Ontology{ID: "onto2", Elements: []*models.OntologyElement{{Name: "Isole"}}}
+	for i := 0; i < 12; i++ {
+		for j := i + 1; j < 12; j++ {
+			clique.Relations = append(clique.Relations, &models.Relation{Source: fmt.Sprintf("N%d", i), Type: "lie", Target: fmt.Sprintf("N%d", j)})
+		}
+	}
+	ms.AddOntology(clique)
+	g := New(ms)
+
+	result, err := g.Paths("A", "C", PathOptions{Mode: PathModeAllSimple})
+	if err != nil {
+		t.Fatalf("Paths failed: %v", err)
+	}
+	if len(result.Paths) != 1 || result.Truncated {
+		t.Errorf("Expected a single path from A to C, got %+v", result.Paths)
+	}
+
+	result, err = g.Paths("N0", "Isole", PathOptions{Mode: PathModeAllSimple, MaxLength: MaxPathLength})
+	if err != nil {
+		t.Fatalf("Paths failed: %v", err)
+	}
+	if len(result.Paths) != 0 || !result.Truncated {
+		t.Errorf("Expected the exploration budget to truncate the enumeration, got %+v", result)
+	}
+}
+
+func TestAnalytics(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID: "onto1",
+		Relations: []*models.Relation{
+			// Deux triangles reliés par le pont C-D, et un couple isolé
+			{Source: "A", Type: "lie", Target: "B"},
+			{Source: "B", Type: "lie", Target: "C"},
+			{Source: "C", Type: "lie", Target: "A"},
+			{Source: "C", Type: "lie", Target: "D"},
+			{Source: "D", Type: "lie", Target: "E"},
+			{Source: "E", Type: "lie", Target: "F"},
+			{Source: "F", Type: "lie", Target: "D"},
+			{Source: "X", Type: "lie", Target: "Y"},
+		},
+	})
+	g := New(ms)
+
+	analytics, err := g.Analytics("onto1")
+	if err != nil {
+		t.Fatalf("Analytics failed: %v", err)
+	}
+	if analytics.Nodes != 8 || analytics.Edges != 8 {
+		t.Errorf("Expected 8 nodes and 8 edges, got %d and %d", analytics.Nodes, analytics.Edges)
+	}
+	if len(analytics.Components) != 2 || analytics.Components[0].Size != 6 || analytics.Components[1].Size != 2 {
+		t.Errorf("Expected components of 6 and 2 nodes, got %+v", analytics.Components)
+	}
+	if len(analytics.Communities) != 3 || analytics.Modularity <= 0 {
+		t.Errorf("Expected the two triangles and the couple as communities, got %+v (Q=%f)", analytics.Communities, analytics.Modularity)
+	}
+
+	byName := make(map[string]NodeCentrality)
+	sum := 0.0
+	for _, c := range analytics.Centrality {
+		byName[c.Name] = c
+		sum += c.PageRank
+	}
+	if byName["C"].Betweenness <= byName["A"].Betweenness || byName["C"].Degree != 3 {
+		t.Errorf("Expected C to bridge the triangles, got %+v", byName["C"])
+	}
+	if sum < 0.999 || sum > 1.001 {
+		t.Errorf("Expected PageRank to sum to 1, got %f", sum)
+	}
+
+	// Les mesures sont conservées jusqu'à la modification de l'ontologie
+	if cached, _ := g.Analytics("onto1"); cached != analytics {
+		t.Error("Expected cached analytics for an unchanged ontology")
+	}
+	ms.UpdateOntology(&models.Ontology{ID: "onto1", Relations: []*models.Relation{{Source: "A", Type: "lie", Target: "B"}}})
+	if updated, _ := g.Analytics("onto1"); updated == analytics || updated.Nodes != 2 {
+		t.Errorf("Expected analytics recomputed for the new version, got %+v", updated)
+	}
+	if _, err := g.Analytics("unknown"); err == nil {
+		t.Error("Expected error for an unknown ontology, got nil")
+	}
+}
+
+func TestRelationTypeInference(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID: "onto1",
+		Elements: []*models.OntologyElement{
+			{Name: "France", Type: "Pays"},
+			{Name: "Ile_De_France", Type: "Région"},
+			{Name: "Paris", Type: "Ville"},
+		},
+		Relations: []*models.Relation{
+			{Source: "France", Type: "contient", Target: "Ile_De_France"},
+			{Source: "Ile_De_France", Type: "contient", Target: "Paris"},
+			{Source: "Paris", Type: "jumelée_avec", Target: "Rome"},
+		},
+	})
+	g := New(ms)
+	if relations := g.Relations("Paris"); len(relations) != 2 {
+		t.Fatalf("Expected only declared relations without schema, got %+v", relations)
+	}
+
+	err := ms.SetRelationTypes("onto1", []*models.RelationType{
+		{Name: "contient", Transitive: true, Domain: "Pays"},
+		{Name: "est_partie_de", InverseOf: "contient"},
+		{Name: "jumelée_avec", Symmetric: true},
+	})
+	if err != nil {
+		t.Fatalf("SetRelationTypes failed: %v", err)
+	}
+
+	inferred := make(map[string]*models.Relation)
+	for _, relation := range g.Relations("Paris") {
+		if relation.Inference != nil {
+			inferred[relation.Source+" "+relation.Type+" "+relation.Target] = relation
+		}
+	}
+	for _, expected := range []string{
+		"Paris est_partie_de Ile_De_France",
+		"France contient Paris",
+		"Paris est_partie_de France",
+	} {
+		if inferred[expected] == nil {
+			t.Errorf("Expected inferred relation %q, got %v", expected, inferred)
+		}
+	}
+	if r := inferred["France contient Paris"]; r != nil && (r.Inference.Rule != "transitive:contient" || len(r.Inference.Premises) != 2) {
+		t.Errorf("Expected a transitive inference with two premises, got %+v", r.Inference)
+	}
+	if rome := g.Relations("Rome"); len(rome) != 2 || rome[1].Source != "Rome" || rome[1].Inference == nil {
+		t.Errorf("Expected the symmetric relation from Rome, got %+v", rome)
+	}
+
+	// Le chemin le plus court emprunte la relation déduite par transitivité
+	result, _ := g.Paths("Paris", "France", PathOptions{Direction: DirectionOutgoing, Types: []string{"est_partie_de"}})
+	if len(result.Paths) != 1 || result.Paths[0].Length != 1 || result.Paths[0].Relations[0].Inference == nil {
+		t.Errorf("Expected a one-hop inferred path, got %+v", result.Paths)
+	}
+
+	types, violations, err := g.RelationTypes("onto1")
+	if err != nil || len(types) != 3 {
+		t.Fatalf("Expected 3 relation types, got %+v (%v)", types, err)
+	}
+	if len(violations) != 1 || violations[0].Relation.Source != "Ile_De_France" {
+		t.Errorf("Expected the domain violation of Ile_De_France, got %+v", violations)
+	}
+}
+
+func TestSchemaSubtypes(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID: "onto1",
+		Elements: []*models.OntologyElement{
+			{Name: "Hugo", Type: "Personne/Auteur"},
+			{Name: "Scribe", Type: "Écrivain_Public"},
+			{Name: "Editeur", Type: "Organisation"},
+			{Name: "Livre", Type: "Oeuvre"},
+		},
+		Relations: []*models.Relation{
+			{Source: "Hugo", Type: "écrit", Target: "Livre"},
+			{Source: "Scribe", Type: "écrit", Target: "Livre"},
+			{Source: "Editeur", Type: "écrit", Target: "Livre"},
+		},
+		TypeParents:   map[string]string{"écrivain public": "Personne"},
+		RelationTypes: []*models.RelationType{{Name: "écrit", Domain: "personne", Range: "Oeuvre"}},
+	})
+	g := New(ms)
+
+	// Ni le type combiné ni le sous-type ne sont signalés
+	_, violations, err := g.RelationTypes("onto1")
+	if err != nil {
+		t.Fatalf("RelationTypes failed: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Relation.Source != "Editeur" {
+		t.Errorf("Expected only the domain violation of Editeur, got %+v", violations)
+	}
+}
+
+func TestRuleInference(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID: "onto1",
+		Relations: []*models.Relation{
+			{Source: "Avocat", Type: "est_un", Target: "Juriste"},
+			{Source: "Juriste", Type: "est_un", Target: "Professionnel"},
+			{Source: "Cabinet", Type: "emploie", Target: "Avocat"},
+		},
+		Rules: []*models.Rule{
+			{Name: "sous-classe", Rule: "A est_un B, B est_un C => A est_un C"},
+			{Name: "emploi", Rule: "?employeur emploie ?employe => ?employe travaille_pour ?employeur"},
+			{Name: "professionnels", Rule: "X travaille_pour Y, X est_un Professionnel => Y emploie_des Professionnel"},
+		},
+	})
+	g := New(ms)
+
+	inferred, err := g.InferredRelations("onto1")
+	if err != nil {
+		t.Fatalf("InferredRelations failed: %v", err)
+	}
+	byKey := make(map[string]*models.Relation)
+	for _, relation := range inferred {
+		byKey[relation.Source+" "+relation.Type+" "+relation.Target] = relation
+	}
+	if len(inferred) != 3 {
+		t.Errorf("Expected 3 inferred relations, got %d", len(inferred))
+	}
+	if r := byKey["Avocat est_un Professionnel"]; r == nil || r.Inference.Rule != "rule:sous-classe" || len(r.Inference.Premises) != 2 {
+		t.Errorf("Expected Avocat est_un Professionnel from the subclass rule, got %+v", r)
+	}
+	// La troisième règle s'appuie sur les conclusions des deux autres
+	if r := byKey["Cabinet emploie_des Professionnel"]; r == nil || r.Inference.Premises[0].Inference == nil || r.Inference.Premises[1].Inference == nil {
+		t.Errorf("Expected a conclusion derived from inferred premises, got %+v", r)
+	}
+
+	// Les relations déduites sont recalculées lorsque l'ontologie change
+	if err := ms.SetRules("onto1", nil); err != nil {
+		t.Fatalf("SetRules failed: %v", err)
+	}
+	if inferred, _ = g.InferredRelations("onto1"); len(inferred) != 0 {
+		t.Errorf("Expected no inferred relation without rules, got %+v", inferred)
+	}
+
+	for _, invalid := range []string{"A est_un B", "A est_un B => C est_un A", "A est_un => B est_un A", "A x B, C y D, E z F => A q F"} {
+		if err := ParseRule(&models.Rule{Rule: invalid}); err == nil {
+			t.Errorf("Expected error for rule %q, got nil", invalid)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID: "onto1",
+		Elements: []*models.OntologyElement{
+			{Name: "Ministere", Type: "Administration"},
+			{Name: "Club", Type: "Organisation"},
+			{Name: "Agent_Public", Type: "Rôle"},
+			{Name: "Entraineur", Type: "Rôle"},
+			{Name: "Neutralite", Type: "Concept"},
+			{Name: "Performance", Type: "Concept"},
+		},
+		Relations: []*models.Relation{
+			{Source: "Ministere", Type: "emploie", Target: "Agent_Public"},
+			{Source: "Club", Type: "emploie", Target: "Entraineur"},
+			{Source: "Agent_Public", Type: "respecte", Target: "Neutralite"},
+			{Source: "Entraineur", Type: "vise", Target: "Performance"},
+		},
+		TypeParents: map[string]string{"Administration": "Organisation"},
+	})
+	g := New(ms)
+
+	// Un type inclut ses sous-types : le ministère est une organisation
+	result, err := g.Query(`MATCH (o:Organisation)-[e:emploie]->(r:Rôle)-[]-(c {name: "Neutralite"}) RETURN o, e, r, c.type`, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if strings.Join(result.Columns, ",") != "o,e,r,c.type" {
+		t.Errorf("Unexpected columns: %v", result.Columns)
+	}
+	if len(result.Rows) != 1 || strings.Join(result.Rows[0], ",") != "Ministere,emploie,Agent_Public,Concept" {
+		t.Fatalf("Expected the ministry row only, got %v", result.Rows)
+	}
+
+	result, _ = g.Query(`match (o)-->(r:Rôle), (r)-->(c:Concept) where c.name starts with "perf" return o.name, c limit 5`, nil)
+	if len(result.Rows) != 1 || result.Rows[0][0] != "Club" {
+		t.Errorf("Expected the club row, got %v", result.Rows)
+	}
+
+	result, _ = g.Query(`MATCH (r:Rôle)<-[:emploie]-(o) RETURN r LIMIT 1`, nil)
+	if len(result.Rows) != 1 || !result.Truncated {
+		t.Errorf("Expected one row and a truncated result, got %+v", result)
+	}
+
+	for _, invalid := range []string{
+		`MATCH (a)-[:emploie]->(b)`,
+		`MATCH (a) WHERE z.name = "x" RETURN a`,
+		`MATCH (a)<-[]->(b) RETURN a`,
+		`MATCH (a) RETURN a LIMIT 0`,
+		`MATCH (a {name: "x) RETURN a`,
+		`MATCH (a) WHERE a.name =~ "(a{100}){100}" RETURN a`,
+		`MATCH (a) WHERE a.name =~ "` + strings.Repeat("a", 201) + `" RETURN a`,
+	} {
+		if _, err := g.Query(invalid, nil); err == nil {
+			t.Errorf("Expected error for %q, got nil", invalid)
+		}
+	}
+}
+
+func TestResolvedEndpoints(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	elements := []*models.OntologyElement{{Name: "Cour_de_cassation", Type: "Juridiction"}, {Name: "Etat"}}
+	relations := []*models.Relation{{Source: "la Cour de cassation", Type: "relève_de", Target: "l'Etat"}}
+	storage.ResolveRelationEndpoints(elements, relations)
+	ms.AddOntology(&models.Ontology{ID: "onto1", Elements: elements, Relations: relations})
+	g := New(ms)
+
+	// Les arêtes relient les éléments résolus et non le texte des extrémités
+	edges := g.Edges("onto1", CanonicalID("Etat"), DirectionIncoming, nil)
+	if len(edges) != 1 || edges[0].Source != CanonicalID("Cour_de_cassation") {
+		t.Fatalf("Expected an edge from Cour_de_cassation to Etat, got %+v", edges)
+	}
+	if label := g.Label("onto1", edges[0].Source); label != "Cour_de_cassation" {
+		t.Errorf("Expected the element name as label, got %s", label)
+	}
+}
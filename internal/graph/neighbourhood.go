@@ -0,0 +1,180 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Limites appliquées à l'extraction de voisinage
+const (
+	// DefaultDepth est le nombre de sauts parcourus par défaut
+	DefaultDepth = 1
+	// MaxDepth borne le nombre de sauts parcourus
+	MaxDepth = 5
+	// DefaultMaxNodes borne par défaut le nombre de nœuds retournés
+	DefaultMaxNodes = 200
+	// DefaultMaxEdges borne par défaut le nombre d'arêtes retournées
+	DefaultMaxEdges = 500
+)
+
+// ErrElementNotFound signale un élément absent de toutes les ontologies indexées
+var ErrElementNotFound = errors.New("element not found")
+
+// Node est un nœud d'un sous-graphe extrait
+type Node struct {
+	OntologyID  string `json:"ontology_id"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Depth est le nombre de sauts depuis l'élément de départ
+	Depth int `json:"depth"`
+}
+
+// SubgraphEdge est une relation d'un sous-graphe extrait, entre deux identifiants de nœuds
+type SubgraphEdge struct {
+	OntologyID  string `json:"ontology_id"`
+	Source      string `json:"source"`
+	Target      string `json:"target"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Inferred    bool   `json:"inferred,omitempty"`
+}
+
+// Subgraph est le voisinage d'un élément
+type Subgraph struct {
+	Nodes []Node         `json:"nodes"`
+	Edges []SubgraphEdge `json:"edges"`
+	// Truncated indique que le voisinage a été tronqué par les limites de nœuds ou d'arêtes
+	Truncated bool `json:"truncated"`
+}
+
+// NeighbourhoodOptions contient les critères d'extraction d'un voisinage
+type NeighbourhoodOptions struct {
+	Depth     int
+	Types     []string
+	Direction string
+	MaxNodes  int
+	MaxEdges  int
+}
+
+// withDefaults complète les critères non renseignés
+func (opts NeighbourhoodOptions) withDefaults() NeighbourhoodOptions {
+	if opts.Depth <= 0 {
+		opts.Depth = DefaultDepth
+	}
+	if opts.Depth > MaxDepth {
+		opts.Depth = MaxDepth
+	}
+	if opts.Direction == "" {
+		opts.Direction = DirectionBoth
+	}
+	if opts.MaxNodes <= 0 {
+		opts.MaxNodes = DefaultMaxNodes
+	}
+	if opts.MaxEdges <= 0 {
+		opts.MaxEdges = DefaultMaxEdges
+	}
+	return opts
+}
+
+// ValidDirection indique si le sens de parcours est reconnu
+func ValidDirection(direction string) bool {
+	switch direction {
+	case DirectionOutgoing, DirectionIncoming, DirectionBoth:
+		return true
+	}
+	return false
+}
+
+// node retourne la description d'un nœud de l'ontologie
+func (og *ontologyGraph) node(id string, depth int) Node {
+	n := Node{OntologyID: og.ontology.ID, ID: id, Name: og.labels[id], Depth: depth}
+	if element, exists := og.elements[id]; exists {
+		n.Type = element.Type
+		n.Description = element.Description
+	}
+	return n
+}
+
+// contains indique si le nœud est un élément ou une extrémité de relation de l'ontologie
+func (og *ontologyGraph) contains(id string) bool {
+	_, exists := og.labels[id]
+	return exists
+}
+
+// Neighbourhood extrait, dans chaque ontologie contenant l'élément, les nœuds et relations
+// atteignables en au plus opts.Depth sauts, par un parcours en largeur
+func (g *Graph) Neighbourhood(elementName string, opts NeighbourhoodOptions) (*Subgraph, error) {
+	opts = opts.withDefaults()
+	if !ValidDirection(opts.Direction) {
+		return nil, fmt.Errorf("invalid direction: %s", opts.Direction)
+	}
+
+	start := CanonicalID(elementName)
+	subgraph := &Subgraph{Nodes: []Node{}, Edges: []SubgraphEdge{}}
+	found := false
+	for _, og := range g.graphs(nil) {
+		if !og.contains(start) {
+			continue
+		}
+		found = true
+		if !og.neighbourhood(start, opts, subgraph) {
+			subgraph.Truncated = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrElementNotFound, elementName)
+	}
+	return subgraph, nil
+}
+
+// neighbourhood ajoute au sous-graphe le voisinage de start dans l'ontologie.
+// Retourne false lorsque l'une des limites de nœuds ou d'arêtes est atteinte.
+func (og *ontologyGraph) neighbourhood(start string, opts NeighbourhoodOptions, subgraph *Subgraph) bool {
+	if len(subgraph.Nodes) >= opts.MaxNodes {
+		return false
+	}
+	depths := map[string]int{start: 0}
+	subgraph.Nodes = append(subgraph.Nodes, og.node(start, 0))
+	seen := make(map[*Edge]bool)
+
+	frontier := []string{start}
+	for depth := 1; depth <= opts.Depth && len(frontier) > 0; depth++ {
+		var next []string
+		for _, id := range frontier {
+			for _, edge := range og.edges(id, opts.Direction, opts.Types) {
+				if seen[edge] {
+					continue
+				}
+				neighbour := edge.Target
+				if neighbour == id {
+					neighbour = edge.Source
+				}
+				if _, visited := depths[neighbour]; !visited {
+					if len(subgraph.Nodes) >= opts.MaxNodes {
+						return false
+					}
+					depths[neighbour] = depth
+					subgraph.Nodes = append(subgraph.Nodes, og.node(neighbour, depth))
+					next = append(next, neighbour)
+				}
+				if len(subgraph.Edges) >= opts.MaxEdges {
+					return false
+				}
+				seen[edge] = true
+				subgraph.Edges = append(subgraph.Edges, SubgraphEdge{
+					OntologyID:  og.ontology.ID,
+					Source:      edge.Source,
+					Target:      edge.Target,
+					Type:        edge.Type,
+					Description: edge.Relation.Description,
+					Inferred:    edge.Inferred(),
+				})
+			}
+		}
+		frontier = next
+	}
+	return true
+}
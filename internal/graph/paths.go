@@ -0,0 +1,402 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+)
+
+// Modes de recherche de chemins
+const (
+	PathModeShortest  = "shortest"
+	PathModeKShortest = "k_shortest"
+	PathModeAllSimple = "all"
+)
+
+// Limites appliquées à la recherche de chemins
+const (
+	// DefaultK est le nombre de chemins retournés par défaut en mode k_shortest
+	DefaultK = 3
+	// MaxK borne le nombre de chemins retournés en mode k_shortest
+	MaxK = 20
+	// DefaultPathLength est la longueur maximale par défaut d'un chemin énuméré en mode all, en relations
+	DefaultPathLength = 4
+	// MaxPathLength borne la longueur d'un chemin
+	MaxPathLength = 8
+	// DefaultMaxPaths borne par défaut le nombre de chemins énumérés en mode all
+	DefaultMaxPaths = 100
+	// maxPathsCeiling borne le nombre de chemins énumérés, quelle que soit la demande
+	maxPathsCeiling = 1000
+	// maxPathSteps borne le nombre de sauts explorés par ontologie lors de l'énumération des chemins simples
+	maxPathSteps = 1000000
+)
+
+// PathStep est une relation parcourue le long d'un chemin
+type PathStep struct {
+	Source      string `json:"source"`
+	Type        string `json:"type"`
+	Target      string `json:"target"`
+	Description string `json:"description,omitempty"`
+	// Inverse indique que la relation a été parcourue de sa cible vers sa source
+	Inverse bool `json:"inverse"`
+	// Inference explique une relation déduite des propriétés de son type
+	Inference *models.Inference `json:"inference,omitempty"`
+}
+
+// Path est une chaîne de relations reliant deux éléments d'une même ontologie
+type Path struct {
+	OntologyID string     `json:"ontology_id"`
+	Length     int        `json:"length"`
+	Nodes      []string   `json:"nodes"`
+	Relations  []PathStep `json:"relations"`
+}
+
+// PathResult contient les chemins trouvés entre deux éléments
+type PathResult struct {
+	Paths []Path `json:"paths"`
+	// Truncated indique que l'énumération a été interrompue par la limite de chemins ou d'exploration
+	Truncated bool `json:"truncated"`
+}
+
+// PathOptions contient les critères de recherche de chemins
+type PathOptions struct {
+	Mode      string
+	K         int
+	MaxLength int
+	MaxPaths  int
+	Types     []string
+	Direction string
+}
+
+// withDefaults complète les critères non renseignés
+func (opts PathOptions) withDefaults() PathOptions {
+	if opts.Mode == "" {
+		opts.Mode = PathModeShortest
+	}
+	if opts.K <= 0 {
+		opts.K = DefaultK
+	}
+	if opts.K > MaxK {
+		opts.K = MaxK
+	}
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = MaxPathLength
+		if opts.Mode == PathModeAllSimple {
+			opts.MaxLength = DefaultPathLength
+		}
+	}
+	if opts.MaxLength > MaxPathLength {
+		opts.MaxLength = MaxPathLength
+	}
+	if opts.MaxPaths <= 0 {
+		opts.MaxPaths = DefaultMaxPaths
+	}
+	if opts.MaxPaths > maxPathsCeiling {
+		opts.MaxPaths = maxPathsCeiling
+	}
+	if opts.Direction == "" {
+		opts.Direction = DirectionBoth
+	}
+	return opts
+}
+
+// ValidPathMode indique si le mode de recherche de chemins est reconnu
+func ValidPathMode(mode string) bool {
+	switch mode {
+	case PathModeShortest, PathModeKShortest, PathModeAllSimple:
+		return true
+	}
+	return false
+}
+
+// hop est le parcours d'une arête depuis le nœud from vers le nœud to
+type hop struct {
+	edge *Edge
+	from string
+	to   string
+}
+
+// route est une suite de sauts consécutifs
+type route []hop
+
+// key identifie une route par les arêtes parcourues
+func (r route) key() string {
+	var b strings.Builder
+	for _, h := range r {
+		fmt.Fprintf(&b, "%d>%s;", h.edge.index, h.to)
+	}
+	return b.String()
+}
+
+// Paths recherche, dans chaque ontologie contenant les deux éléments, les chemins qui les relient.
+// Le mode shortest retourne un plus court chemin, k_shortest les K plus courts chemins simples
+// (algorithme de Yen) et all tous les chemins simples d'au plus MaxLength relations.
+func (g *Graph) Paths(from, to string, opts PathOptions) (*PathResult, error) {
+	opts = opts.withDefaults()
+	if !ValidPathMode(opts.Mode) {
+		return nil, fmt.Errorf("invalid path mode: %s", opts.Mode)
+	}
+	if !ValidDirection(opts.Direction) {
+		return nil, fmt.Errorf("invalid direction: %s", opts.Direction)
+	}
+
+	source, target := CanonicalID(from), CanonicalID(to)
+	result := &PathResult{Paths: []Path{}}
+	foundSource, foundTarget := false, false
+	for _, og := range g.graphs(nil) {
+		foundSource = foundSource || og.contains(source)
+		foundTarget = foundTarget || og.contains(target)
+		if !og.contains(source) || !og.contains(target) {
+			continue
+		}
+
+		var routes []route
+		switch opts.Mode {
+		case PathModeShortest:
+			if r := og.shortestRoute(source, target, opts, nil, nil); r != nil {
+				routes = []route{r}
+			}
+		case PathModeKShortest:
+			routes = og.kShortestRoutes(source, target, opts)
+		case PathModeAllSimple:
+			var truncated bool
+			routes, truncated = og.simpleRoutes(source, target, opts)
+			result.Truncated = result.Truncated || truncated
+		}
+		for _, r := range routes {
+			result.Paths = append(result.Paths, og.path(source, r))
+		}
+	}
+	if !foundSource {
+		return nil, fmt.Errorf("%w: %s", ErrElementNotFound, from)
+	}
+	if !foundTarget {
+		return nil, fmt.Errorf("%w: %s", ErrElementNotFound, to)
+	}
+
+	sort.SliceStable(result.Paths, func(i, j int) bool {
+		return result.Paths[i].Length < result.Paths[j].Length
+	})
+	switch opts.Mode {
+	case PathModeShortest:
+		if len(result.Paths) > 1 {
+			result.Paths = result.Paths[:1]
+		}
+	case PathModeKShortest:
+		if len(result.Paths) > opts.K {
+			result.Paths = result.Paths[:opts.K]
+		}
+	case PathModeAllSimple:
+		if len(result.Paths) > opts.MaxPaths {
+			result.Paths = result.Paths[:opts.MaxPaths]
+			result.Truncated = true
+		}
+	}
+	return result, nil
+}
+
+// hops retourne les sauts possibles depuis un nœud, en ignorant les relations réflexives
+func (og *ontologyGraph) hops(node string, opts PathOptions) []hop {
+	var hops []hop
+	for _, edge := range og.edges(node, opts.Direction, opts.Types) {
+		next := edge.Target
+		if next == node {
+			next = edge.Source
+		}
+		if next == node {
+			continue
+		}
+		hops = append(hops, hop{edge: edge, from: node, to: next})
+	}
+	return hops
+}
+
+// shortestRoute retourne un plus court chemin par parcours en largeur, en évitant les nœuds
+// et arêtes bloqués, ou nil si la cible n'est pas atteignable en au plus MaxLength relations
+func (og *ontologyGraph) shortestRoute(source, target string, opts PathOptions, blockedNodes map[string]bool, blockedEdges map[*Edge]bool) route {
+	if source == target {
+		return nil
+	}
+	parents := map[string]hop{}
+	visited := map[string]bool{source: true}
+	frontier := []string{source}
+	for depth := 1; depth <= opts.MaxLength && len(frontier) > 0; depth++ {
+		var next []string
+		for _, node := range frontier {
+			for _, h := range og.hops(node, opts) {
+				if visited[h.to] || blockedNodes[h.to] || blockedEdges[h.edge] {
+					continue
+				}
+				visited[h.to] = true
+				parents[h.to] = h
+				if h.to == target {
+					var r route
+					for n := target; n != source; n = parents[n].from {
+						r = append(route{parents[n]}, r...)
+					}
+					return r
+				}
+				next = append(next, h.to)
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
+
+// kShortestRoutes retourne les K plus courts chemins simples selon l'algorithme de Yen
+func (og *ontologyGraph) kShortestRoutes(source, target string, opts PathOptions) []route {
+	first := og.shortestRoute(source, target, opts, nil, nil)
+	if first == nil {
+		return nil
+	}
+	accepted := []route{first}
+	known := map[string]bool{first.key(): true}
+	var candidates []route
+
+	for len(accepted) < opts.K {
+		previous := accepted[len(accepted)-1]
+		for i := range previous {
+			spur := previous[i].from
+			root := previous[:i]
+
+			// Bloquer les arêtes qui prolongent la même racine dans les chemins déjà retenus
+			blockedEdges := make(map[*Edge]bool)
+			for _, r := range accepted {
+				if len(r) > i && r[:i].key() == root.key() {
+					blockedEdges[r[i].edge] = true
+				}
+			}
+			// Bloquer les nœuds de la racine pour garder le chemin simple
+			blockedNodes := make(map[string]bool)
+			for _, h := range root {
+				blockedNodes[h.from] = true
+			}
+
+			remaining := opts
+			remaining.MaxLength = opts.MaxLength - i
+			tail := og.shortestRoute(spur, target, remaining, blockedNodes, blockedEdges)
+			if tail == nil {
+				continue
+			}
+			candidate := append(append(route{}, root...), tail...)
+			if key := candidate.key(); !known[key] {
+				known[key] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return len(candidates[a]) < len(candidates[b])
+		})
+		accepted = append(accepted, candidates[0])
+		candidates = candidates[1:]
+	}
+	return accepted
+}
+
+// distinctHops retire les sauts qui doublent un saut déjà retenu vers le même voisin : même type de relation,
+// ou relation déduite par inverse ou symétrie de la relation parcourue par ce saut.
+// Les relations déclarées précèdent les relations déduites et sont donc conservées.
+func distinctHops(hops []hop) []hop {
+	type hopKey struct{ to, relationType string }
+	seen := make(map[hopKey]bool)
+	relations := make(map[string]map[*models.Relation]bool)
+	distinct := hops[:0:0]
+	for _, h := range hops {
+		key := hopKey{h.to, h.edge.Type}
+		if seen[key] {
+			continue
+		}
+		if inference := h.edge.Relation.Inference; inference != nil && len(inference.Premises) == 1 && relations[h.to][inference.Premises[0]] {
+			continue
+		}
+		seen[key] = true
+		if relations[h.to] == nil {
+			relations[h.to] = make(map[*models.Relation]bool)
+		}
+		relations[h.to][h.edge.Relation] = true
+		distinct = append(distinct, h)
+	}
+	return distinct
+}
+
+// simpleRoutes énumère par parcours en profondeur les chemins simples d'au plus MaxLength relations.
+// Le second résultat indique que l'énumération a atteint la limite MaxPaths ou maxPathSteps.
+func (og *ontologyGraph) simpleRoutes(source, target string, opts PathOptions) ([]route, bool) {
+	var routes []route
+	truncated := false
+	steps := 0
+	onPath := map[string]bool{source: true}
+	var current route
+	// Les sauts d'un nœud sont calculés une seule fois par énumération
+	hopsByNode := make(map[string][]hop)
+
+	var walk func(node string)
+	walk = func(node string) {
+		if truncated || len(current) >= opts.MaxLength {
+			return
+		}
+		hops, cached := hopsByNode[node]
+		if !cached {
+			hops = distinctHops(og.hops(node, opts))
+			hopsByNode[node] = hops
+		}
+		for _, h := range hops {
+			if onPath[h.to] {
+				continue
+			}
+			steps++
+			if steps > maxPathSteps {
+				truncated = true
+				return
+			}
+			current = append(current, h)
+			if h.to == target {
+				if len(routes) >= opts.MaxPaths {
+					truncated = true
+					return
+				}
+				routes = append(routes, append(route{}, current...))
+			} else {
+				onPath[h.to] = true
+				walk(h.to)
+				onPath[h.to] = false
+			}
+			current = current[:len(current)-1]
+			if truncated {
+				return
+			}
+		}
+	}
+	if source != target {
+		walk(source)
+	}
+	return routes, truncated
+}
+
+// path convertit une route en chemin décrit par les noms des éléments et les relations parcourues
+func (og *ontologyGraph) path(source string, r route) Path {
+	p := Path{
+		OntologyID: og.ontology.ID,
+		Length:     len(r),
+		Nodes:      []string{og.labels[source]},
+		Relations:  make([]PathStep, 0, len(r)),
+	}
+	for _, h := range r {
+		p.Nodes = append(p.Nodes, og.labels[h.to])
+		p.Relations = append(p.Relations, PathStep{
+			Source:      h.edge.Relation.Source,
+			Type:        h.edge.Type,
+			Target:      h.edge.Relation.Target,
+			Description: h.edge.Relation.Description,
+			Inverse:     h.edge.Source != h.from,
+			Inference:   h.edge.Relation.Inference,
+		})
+	}
+	return p
+}
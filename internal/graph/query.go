@@ -0,0 +1,757 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/chrlesur/ontology-server/internal/regexlimit"
+	"github.com/chrlesur/ontology-server/internal/storage"
+)
+
+// Limites appliquées à l'exécution d'une requête de motifs
+const (
+	// DefaultQueryLimit est le nombre de lignes retournées sans clause LIMIT
+	DefaultQueryLimit = 100
+	// MaxQueryLimit borne le nombre de lignes retournées
+	MaxQueryLimit = 1000
+	// maxQuerySteps borne le nombre de correspondances partielles explorées par ontologie
+	maxQuerySteps = 1000000
+)
+
+// ErrInvalidQuery signale une requête de motifs mal formée
+var ErrInvalidQuery = errors.New("invalid query")
+
+// QueryResult est la table des liaisons d'une requête de motifs
+type QueryResult struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+	// Truncated indique que la limite de lignes ou d'exploration a été atteinte
+	Truncated bool `json:"truncated"`
+}
+
+// condition compare une propriété d'une variable à une valeur
+type condition struct {
+	property string
+	operator string
+	value    string
+	pattern  *regexp.Regexp
+}
+
+// queryNode est un nœud du motif : (variable:Type {name: "..."})
+type queryNode struct {
+	name       string
+	labels     []string
+	conditions []condition
+}
+
+// queryRelation est une relation du motif entre deux nœuds : -[variable:type1|type2]->
+type queryRelation struct {
+	name       string
+	from, to   int
+	types      []string
+	direction  string
+	conditions []condition
+}
+
+// returnItem est une colonne du résultat : une variable ou l'une de ses propriétés
+type returnItem struct {
+	column   string
+	variable string
+	property string
+}
+
+// patternQuery est une requête analysée
+type patternQuery struct {
+	nodes     []*queryNode
+	relations []*queryRelation
+	// paths liste, pour chaque chemin du motif, son premier nœud et ses relations dans l'ordre
+	paths   []queryPath
+	returns []returnItem
+	limit   int
+}
+
+// queryPath est un chemin du motif
+type queryPath struct {
+	start     int
+	relations []int
+}
+
+// token est un lexème de la requête
+type token struct {
+	kind  string // ident, string, number, punct
+	value string
+}
+
+// tokenize découpe une requête en lexèmes
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				b.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidQuery)
+			}
+			tokens = append(tokens, token{"string", b.String()})
+			i = j + 1
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, token{"number", string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{"ident", string(runes[i:j])})
+			i = j
+		case r == '<' && i+1 < len(runes) && runes[i+1] == '>', r == '=' && i+1 < len(runes) && runes[i+1] == '~':
+			tokens = append(tokens, token{"punct", string(runes[i : i+2])})
+			i += 2
+		case strings.ContainsRune("()[]{}:,.|-<>=*", r):
+			tokens = append(tokens, token{"punct", string(r)})
+			i++
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q", ErrInvalidQuery, r)
+		}
+	}
+	return tokens, nil
+}
+
+// queryParser analyse une requête de la forme
+// MATCH (o:Organisation)-[:emploie]->(r:Rôle) WHERE r.name CONTAINS "agent" RETURN o, r LIMIT 10
+type queryParser struct {
+	tokens    []token
+	pos       int
+	query     *patternQuery
+	variables map[string]int
+}
+
+// parseQuery analyse une requête de motifs
+func parseQuery(text string) (*patternQuery, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens, query: &patternQuery{limit: DefaultQueryLimit}, variables: make(map[string]int)}
+	if err := p.parse(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	return p.query, nil
+}
+
+func (p *queryParser) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return token{}
+}
+
+// accept consomme le lexème s'il a la valeur attendue ; les mots-clés ne sont pas sensibles à la casse
+func (p *queryParser) accept(value string) bool {
+	t := p.peek()
+	if (t.kind == "punct" && t.value == value) || (t.kind == "ident" && strings.EqualFold(t.value, value)) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(value string) error {
+	if !p.accept(value) {
+		return fmt.Errorf("expected %q at %q", value, p.peek().value)
+	}
+	return nil
+}
+
+// name lit un identifiant ou une chaîne, pour les types contenant des espaces ou des accents
+func (p *queryParser) name() (string, error) {
+	t := p.peek()
+	if t.kind != "ident" && t.kind != "string" {
+		return "", fmt.Errorf("expected a name at %q", t.value)
+	}
+	p.pos++
+	return t.value, nil
+}
+
+func (p *queryParser) parse() error {
+	if err := p.expect("MATCH"); err != nil {
+		return err
+	}
+	for {
+		if err := p.parsePath(); err != nil {
+			return err
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if p.accept("WHERE") {
+		for {
+			if err := p.parseCondition(); err != nil {
+				return err
+			}
+			if !p.accept("AND") {
+				break
+			}
+		}
+	}
+
+	if err := p.expect("RETURN"); err != nil {
+		return err
+	}
+	for {
+		if err := p.parseReturnItem(); err != nil {
+			return err
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if p.accept("LIMIT") {
+		t := p.peek()
+		limit, err := strconv.Atoi(t.value)
+		if t.kind != "number" || err != nil || limit < 1 || limit > MaxQueryLimit {
+			return fmt.Errorf("LIMIT must be an integer between 1 and %d", MaxQueryLimit)
+		}
+		p.query.limit = limit
+		p.pos++
+	}
+	if p.pos < len(p.tokens) {
+		return fmt.Errorf("unexpected %q", p.peek().value)
+	}
+	return nil
+}
+
+// parsePath lit un chemin : nœud (relation nœud)*
+func (p *queryParser) parsePath() error {
+	start, err := p.parseNode()
+	if err != nil {
+		return err
+	}
+	path := queryPath{start: start}
+	for p.peek().value == "-" || p.peek().value == "<" {
+		relation, err := p.parseRelation()
+		if err != nil {
+			return err
+		}
+		relation.from = start
+		if relation.to, err = p.parseNode(); err != nil {
+			return err
+		}
+		path.relations = append(path.relations, len(p.query.relations))
+		p.query.relations = append(p.query.relations, relation)
+		start = relation.to
+	}
+	p.query.paths = append(p.query.paths, path)
+	return nil
+}
+
+// parseNode lit un nœud ; une variable déjà rencontrée désigne le même nœud
+func (p *queryParser) parseNode() (int, error) {
+	if err := p.expect("("); err != nil {
+		return 0, err
+	}
+	node := &queryNode{}
+	if p.peek().kind == "ident" {
+		node.name = p.peek().value
+		p.pos++
+	}
+	if p.accept(":") {
+		for {
+			label, err := p.name()
+			if err != nil {
+				return 0, err
+			}
+			node.labels = append(node.labels, label)
+			if !p.accept("|") {
+				break
+			}
+		}
+	}
+	if p.accept("{") {
+		for {
+			property, err := p.name()
+			if err != nil {
+				return 0, err
+			}
+			if err := p.expect(":"); err != nil {
+				return 0, err
+			}
+			value := p.peek()
+			if value.kind != "string" {
+				return 0, fmt.Errorf("expected a string value for property %s", property)
+			}
+			p.pos++
+			node.conditions = append(node.conditions, condition{property: strings.ToLower(property), operator: "=", value: value.value})
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect("}"); err != nil {
+			return 0, err
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return 0, err
+	}
+
+	if node.name != "" {
+		if index, exists := p.variables[node.name]; exists {
+			existing := p.query.nodes[index]
+			existing.labels = append(existing.labels, node.labels...)
+			existing.conditions = append(existing.conditions, node.conditions...)
+			return index, nil
+		}
+		p.variables[node.name] = len(p.query.nodes)
+	}
+	p.query.nodes = append(p.query.nodes, node)
+	return len(p.query.nodes) - 1, nil
+}
+
+// parseRelation lit une relation : -[r:type]->, <-[r:type]-, -[r:type]- ou leurs formes courtes -->, <--, --
+func (p *queryParser) parseRelation() (*queryRelation, error) {
+	relation := &queryRelation{direction: DirectionBoth}
+	incoming := p.accept("<")
+	if err := p.expect("-"); err != nil {
+		return nil, err
+	}
+	if p.accept("[") {
+		if p.peek().kind == "ident" {
+			relation.name = p.peek().value
+			p.pos++
+			if _, exists := p.variables[relation.name]; exists {
+				return nil, fmt.Errorf("variable %s is already a node", relation.name)
+			}
+		}
+		if p.accept(":") {
+			for {
+				relationType, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				relation.types = append(relation.types, relationType)
+				if !p.accept("|") {
+					break
+				}
+			}
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("-"); err != nil {
+		return nil, err
+	}
+	outgoing := p.accept(">")
+	switch {
+	case incoming && outgoing:
+		return nil, fmt.Errorf("a relation cannot point both ways")
+	case incoming:
+		relation.direction = DirectionIncoming
+	case outgoing:
+		relation.direction = DirectionOutgoing
+	}
+	return relation, nil
+}
+
+// parseCondition lit une condition : variable.propriété opérateur "valeur"
+func (p *queryParser) parseCondition() error {
+	variable, err := p.name()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("."); err != nil {
+		return err
+	}
+	property, err := p.name()
+	if err != nil {
+		return err
+	}
+	c := condition{property: strings.ToLower(property)}
+
+	switch {
+	case p.accept("="):
+		c.operator = "="
+	case p.accept("<>"):
+		c.operator = "<>"
+	case p.accept("=~"):
+		c.operator = "=~"
+	case p.accept("CONTAINS"):
+		c.operator = "CONTAINS"
+	case p.accept("STARTS"):
+		c.operator = "STARTS WITH"
+		if err := p.expect("WITH"); err != nil {
+			return err
+		}
+	case p.accept("ENDS"):
+		c.operator = "ENDS WITH"
+		if err := p.expect("WITH"); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", p.peek().value)
+	}
+
+	value := p.peek()
+	if value.kind != "string" {
+		return fmt.Errorf("expected a string after %s", c.operator)
+	}
+	p.pos++
+	c.value = value.value
+	if c.operator == "=~" {
+		if c.pattern, err = regexlimit.Compile(c.value); err != nil {
+			return fmt.Errorf("invalid regular expression %q: %v", c.value, err)
+		}
+	}
+
+	if index, exists := p.variables[variable]; exists {
+		p.query.nodes[index].conditions = append(p.query.nodes[index].conditions, c)
+		return nil
+	}
+	for _, relation := range p.query.relations {
+		if relation.name == variable {
+			relation.conditions = append(relation.conditions, c)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown variable %s", variable)
+}
+
+// parseReturnItem lit une colonne du résultat ; * retourne toutes les variables nommées
+func (p *queryParser) parseReturnItem() error {
+	if p.accept("*") {
+		for _, node := range p.query.nodes {
+			if node.name != "" {
+				p.query.returns = append(p.query.returns, returnItem{column: node.name, variable: node.name})
+			}
+		}
+		for _, relation := range p.query.relations {
+			if relation.name != "" {
+				p.query.returns = append(p.query.returns, returnItem{column: relation.name, variable: relation.name})
+			}
+		}
+		return nil
+	}
+
+	variable, err := p.name()
+	if err != nil {
+		return err
+	}
+	item := returnItem{column: variable, variable: variable}
+	if p.accept(".") {
+		if item.property, err = p.name(); err != nil {
+			return err
+		}
+		item.property = strings.ToLower(item.property)
+		item.column = variable + "." + item.property
+	}
+	if _, exists := p.variables[variable]; !exists && p.relationIndex(variable) < 0 {
+		return fmt.Errorf("unknown variable %s", variable)
+	}
+	p.query.returns = append(p.query.returns, item)
+	return nil
+}
+
+func (p *queryParser) relationIndex(name string) int {
+	for i, relation := range p.query.relations {
+		if relation.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// matches évalue une condition sur une valeur de propriété
+func (c condition) matches(value string) bool {
+	switch c.operator {
+	case "=":
+		if c.property == "name" {
+			return CanonicalID(value) == CanonicalID(c.value)
+		}
+		return strings.EqualFold(value, c.value)
+	case "<>":
+		if c.property == "name" {
+			return CanonicalID(value) != CanonicalID(c.value)
+		}
+		return !strings.EqualFold(value, c.value)
+	case "=~":
+		return c.pattern.MatchString(value)
+	case "CONTAINS":
+		return strings.Contains(strings.ToLower(value), strings.ToLower(c.value))
+	case "STARTS WITH":
+		return strings.HasPrefix(strings.ToLower(value), strings.ToLower(c.value))
+	case "ENDS WITH":
+		return strings.HasSuffix(strings.ToLower(value), strings.ToLower(c.value))
+	}
+	return false
+}
+
+// nodeProperty retourne une propriété d'un nœud : name, type, description ou ontology
+func (og *ontologyGraph) nodeProperty(id, property string) string {
+	switch property {
+	case "name":
+		return og.labels[id]
+	case "id":
+		return id
+	case "ontology":
+		return og.ontology.ID
+	}
+	if element, exists := og.elements[id]; exists {
+		switch property {
+		case "type":
+			return element.Type
+		case "description":
+			return element.Description
+		}
+	}
+	return ""
+}
+
+// edgeProperty retourne une propriété d'une relation : type, description, source ou target
+func edgeProperty(edge *Edge, property string) string {
+	switch property {
+	case "type":
+		return edge.Type
+	case "description":
+		return edge.Relation.Description
+	case "source":
+		return edge.Relation.Source
+	case "target":
+		return edge.Relation.Target
+	case "inferred":
+		return strconv.FormatBool(edge.Inferred())
+	}
+	return ""
+}
+
+// queryExecution contient l'état de la recherche de correspondances dans une ontologie
+type queryExecution struct {
+	og    *ontologyGraph
+	query *patternQuery
+	// accepted contient, pour chaque nœud typé du motif, les types acceptés et leurs sous-types dans l'ontologie
+	accepted []map[string]bool
+	nodes    []string
+	edges    []*Edge
+	used     map[*Edge]bool
+	steps    int
+	emit     func() bool
+	stopped  bool
+}
+
+// Query exécute une requête de motifs sur les ontologies indiquées, ou sur toutes,
+// et retourne la table des liaisons des variables demandées
+func (g *Graph) Query(text string, ontologyIDs []string) (*QueryResult, error) {
+	query, err := parseQuery(text)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QueryResult{Rows: [][]string{}}
+	for _, item := range query.returns {
+		result.Columns = append(result.Columns, item.column)
+	}
+	for _, og := range g.graphs(ontologyIDs) {
+		exec := &queryExecution{
+			og:       og,
+			query:    query,
+			accepted: acceptedTypes(query, g.storage.TypeHierarchy(og.ontology)),
+			nodes:    make([]string, len(query.nodes)),
+			edges:    make([]*Edge, len(query.relations)),
+			used:     make(map[*Edge]bool),
+		}
+		exec.emit = func() bool {
+			if len(result.Rows) >= query.limit {
+				result.Truncated = true
+				return false
+			}
+			result.Rows = append(result.Rows, exec.row())
+			return true
+		}
+		exec.matchPath(0, 0)
+		if exec.steps >= maxQuerySteps {
+			result.Truncated = true
+		}
+		if len(result.Rows) >= query.limit && exec.stopped {
+			break
+		}
+	}
+	return result, nil
+}
+
+// row construit la ligne de résultat des liaisons courantes
+func (e *queryExecution) row() []string {
+	row := make([]string, 0, len(e.query.returns))
+	for _, item := range e.query.returns {
+		value := ""
+		for i, node := range e.query.nodes {
+			if node.name == item.variable {
+				property := item.property
+				if property == "" {
+					property = "name"
+				}
+				value = e.og.nodeProperty(e.nodes[i], property)
+			}
+		}
+		for i, relation := range e.query.relations {
+			if relation.name == item.variable {
+				property := item.property
+				if property == "" {
+					property = "type"
+				}
+				value = edgeProperty(e.edges[i], property)
+			}
+		}
+		row = append(row, value)
+	}
+	return row
+}
+
+// acceptedTypes calcule une fois par ontologie les types acceptés par chaque nœud typé du motif
+func acceptedTypes(query *patternQuery, hierarchy *storage.TypeHierarchy) []map[string]bool {
+	accepted := make([]map[string]bool, len(query.nodes))
+	for i, node := range query.nodes {
+		if len(node.labels) == 0 {
+			continue
+		}
+		accepted[i] = make(map[string]bool)
+		for _, key := range hierarchy.Expand(node.labels) {
+			accepted[i][key] = true
+		}
+	}
+	return accepted
+}
+
+// nodeMatches vérifie les types et conditions d'un nœud du motif ; un type inclut ses sous-types
+func (e *queryExecution) nodeMatches(index int, id string) bool {
+	node := e.query.nodes[index]
+	if len(node.labels) > 0 {
+		element, exists := e.og.elements[id]
+		if !exists {
+			return false
+		}
+		found := false
+		for _, t := range strings.Split(element.Type, "/") {
+			if e.accepted[index][storage.NormalizeType(t)] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, c := range node.conditions {
+		if !c.matches(e.og.nodeProperty(id, c.property)) {
+			return false
+		}
+	}
+	return true
+}
+
+// bindNode lie un nœud du motif, ou vérifie la liaison existante ; retourne la fonction d'annulation
+func (e *queryExecution) bindNode(index int, id string) (bool, func()) {
+	if bound := e.nodes[index]; bound != "" {
+		return bound == id, func() {}
+	}
+	if !e.nodeMatches(index, id) {
+		return false, nil
+	}
+	e.nodes[index] = id
+	return true, func() { e.nodes[index] = "" }
+}
+
+// matchPath recherche les correspondances du chemin p à partir de sa relation r, puis des chemins suivants
+func (e *queryExecution) matchPath(p, r int) {
+	if e.stopped {
+		return
+	}
+	e.steps++
+	if e.steps >= maxQuerySteps {
+		e.stopped = true
+		return
+	}
+	if p >= len(e.query.paths) {
+		if !e.emit() {
+			e.stopped = true
+		}
+		return
+	}
+	path := e.query.paths[p]
+
+	// Le premier nœud d'un chemin est parcouru parmi tous les nœuds de l'ontologie s'il n'est pas encore lié
+	if r == 0 && e.nodes[path.start] == "" {
+		for _, id := range e.og.nodeIDs() {
+			if ok, undo := e.bindNode(path.start, id); ok {
+				e.matchPath(p, r+1)
+				undo()
+			}
+			if e.stopped {
+				return
+			}
+		}
+		return
+	}
+	if r == 0 {
+		r = 1
+	}
+	if r > len(path.relations) {
+		e.matchPath(p+1, 0)
+		return
+	}
+
+	index := path.relations[r-1]
+	relation := e.query.relations[index]
+	from := e.nodes[relation.from]
+	for _, edge := range e.og.edges(from, relation.direction, relation.types) {
+		if e.used[edge] || !e.relationMatches(relation, edge) {
+			continue
+		}
+		to := edge.Target
+		if edge.Target == from && relation.direction != DirectionOutgoing {
+			to = edge.Source
+		}
+		ok, undo := e.bindNode(relation.to, to)
+		if !ok {
+			continue
+		}
+		e.used[edge], e.edges[index] = true, edge
+		e.matchPath(p, r+1)
+		delete(e.used, edge)
+		e.edges[index] = nil
+		undo()
+		if e.stopped {
+			return
+		}
+	}
+}
+
+// relationMatches vérifie les conditions d'une relation du motif
+func (e *queryExecution) relationMatches(relation *queryRelation, edge *Edge) bool {
+	for _, c := range relation.conditions {
+		if !c.matches(edgeProperty(edge, c.property)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,290 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+)
+
+// RulePrefix précède le nom d'une règle d'inférence dans l'explication d'une relation déduite
+const RulePrefix = "rule"
+
+// maxRulePremises borne le nombre de prémisses d'une règle, le coût de la jointure étant exponentiel
+const maxRulePremises = 4
+
+// maxJoinSteps borne le nombre d'arêtes candidates examinées par les jointures des règles d'une ontologie.
+// La jointure s'exécute à chaque modification de l'ontologie : au-delà, les règles ne produisent plus de conclusion.
+const maxJoinSteps = 1000000
+
+// ruleTerm est le sujet ou l'objet d'un motif : une variable ou un élément
+type ruleTerm struct {
+	variable string
+	constant string
+}
+
+// parseTerm interprète un terme : "?x" ou une lettre majuscule éventuellement suivie de chiffres
+// ("A", "B2") désignent une variable, tout autre terme un élément
+func parseTerm(term string) ruleTerm {
+	if strings.HasPrefix(term, "?") && len(term) > 1 {
+		return ruleTerm{variable: term[1:]}
+	}
+	runes := []rune(term)
+	if unicode.IsUpper(runes[0]) && strings.IndexFunc(string(runes[1:]), func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+		return ruleTerm{variable: term}
+	}
+	return ruleTerm{constant: CanonicalID(term)}
+}
+
+// triplePattern est une relation dont le sujet et l'objet peuvent être des variables
+type triplePattern struct {
+	subject   ruleTerm
+	predicate string
+	object    ruleTerm
+}
+
+// compiledRule est une règle analysée
+type compiledRule struct {
+	name       string
+	premises   []triplePattern
+	conclusion triplePattern
+}
+
+// parseTriple analyse un motif "sujet type objet"
+func parseTriple(text string) (triplePattern, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 3 {
+		return triplePattern{}, fmt.Errorf("pattern %q must be \"subject relation_type object\"", strings.TrimSpace(text))
+	}
+	return triplePattern{subject: parseTerm(fields[0]), predicate: fields[1], object: parseTerm(fields[2])}, nil
+}
+
+// ParseRule vérifie une règle de la forme "A est_un B, B est_un C => A est_un C".
+// Les variables de la conclusion doivent apparaître dans les prémisses.
+func ParseRule(rule *models.Rule) error {
+	_, err := compileRule(rule)
+	return err
+}
+
+// compileRule analyse une règle
+func compileRule(rule *models.Rule) (*compiledRule, error) {
+	parts := strings.Split(rule.Rule, "=>")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("rule %q must contain exactly one \"=>\"", rule.Rule)
+	}
+
+	compiled := &compiledRule{name: rule.Name}
+	if compiled.name == "" {
+		compiled.name = strings.TrimSpace(rule.Rule)
+	}
+	bound := make(map[string]bool)
+	for _, text := range strings.Split(parts[0], ",") {
+		premise, err := parseTriple(text)
+		if err != nil {
+			return nil, err
+		}
+		compiled.premises = append(compiled.premises, premise)
+		for _, term := range []ruleTerm{premise.subject, premise.object} {
+			if term.variable != "" {
+				bound[term.variable] = true
+			}
+		}
+	}
+	if len(compiled.premises) > maxRulePremises {
+		return nil, fmt.Errorf("rule %q has more than %d premises", rule.Rule, maxRulePremises)
+	}
+	if !connected(compiled.premises) {
+		return nil, fmt.Errorf("premises of rule %q must be linked by shared variables", rule.Rule)
+	}
+
+	conclusion, err := parseTriple(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	for _, term := range []ruleTerm{conclusion.subject, conclusion.object} {
+		if term.variable != "" && !bound[term.variable] {
+			return nil, fmt.Errorf("variable %s of the conclusion does not appear in the premises", term.variable)
+		}
+	}
+	compiled.conclusion = conclusion
+	return compiled, nil
+}
+
+// connected indique si les prémisses sont reliées entre elles par des variables communes. Une prémisse
+// isolée obligerait la jointure à parcourir toutes les arêtes de son type pour chaque correspondance.
+func connected(premises []triplePattern) bool {
+	reached := map[int]bool{0: true}
+	variables := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for i, premise := range premises {
+			terms := []ruleTerm{premise.subject, premise.object}
+			if !reached[i] {
+				for _, term := range terms {
+					if term.variable != "" && variables[term.variable] {
+						reached[i] = true
+					}
+				}
+			}
+			if !reached[i] {
+				continue
+			}
+			for _, term := range terms {
+				if term.variable != "" && !variables[term.variable] {
+					variables[term.variable] = true
+					changed = true
+				}
+			}
+		}
+	}
+	return len(reached) == len(premises)
+}
+
+// compileRules analyse les règles d'une ontologie ; les règles invalides sont ignorées
+func compileRules(rules []*models.Rule) []*compiledRule {
+	var compiled []*compiledRule
+	for _, rule := range rules {
+		if c, err := compileRule(rule); err == nil {
+			compiled = append(compiled, c)
+		}
+	}
+	return compiled
+}
+
+// bindings associe les variables d'une règle aux identifiants des nœuds
+type bindings map[string]string
+
+// bind unifie un terme avec un nœud ; retourne false en cas de conflit
+func (b bindings) bind(term ruleTerm, node string) bool {
+	if term.variable == "" {
+		return term.constant == node
+	}
+	if bound, exists := b[term.variable]; exists {
+		return bound == node
+	}
+	b[term.variable] = node
+	return true
+}
+
+// resolve retourne le nœud désigné par un terme, ou une chaîne vide pour une variable libre
+func (b bindings) resolve(term ruleTerm) string {
+	if term.variable == "" {
+		return term.constant
+	}
+	return b[term.variable]
+}
+
+// copy duplique les liaisons pour explorer une alternative
+func (b bindings) copy() bindings {
+	c := make(bindings, len(b))
+	for k, v := range b {
+		c[k] = v
+	}
+	return c
+}
+
+// derivation est une conclusion de règle en attente d'ajout au graphe
+type derivation struct {
+	rule     *compiledRule
+	source   string
+	target   string
+	premises []*Edge
+}
+
+// applyRules évalue les règles dont une prémisse correspond à la nouvelle arête, en joignant
+// les autres prémisses avec les arêtes connues (évaluation semi-naïve). steps décompte les arêtes
+// candidates examinées, dans la limite de maxJoinSteps.
+func (og *ontologyGraph) applyRules(rules []*compiledRule, edge *Edge, steps *int) []derivation {
+	var derivations []derivation
+	for _, rule := range rules {
+		for i, premise := range rule.premises {
+			if premise.predicate != edge.Type {
+				continue
+			}
+			b := bindings{}
+			if !b.bind(premise.subject, edge.Source) || !b.bind(premise.object, edge.Target) {
+				continue
+			}
+			matched := make([]*Edge, len(rule.premises))
+			matched[i] = edge
+			og.joinPremises(rule, b, matched, steps, func(b bindings, premises []*Edge) {
+				derivations = append(derivations, derivation{
+					rule:     rule,
+					source:   b.resolve(rule.conclusion.subject),
+					target:   b.resolve(rule.conclusion.object),
+					premises: append([]*Edge(nil), premises...),
+				})
+			})
+		}
+	}
+	return derivations
+}
+
+// nextPremise choisit la prémisse à joindre : la première non encore satisfaite dont le sujet ou l'objet
+// est lié, pour parcourir les listes d'adjacence plutôt que toutes les arêtes d'un type. Retourne -1
+// lorsque toutes les prémisses sont satisfaites.
+func nextPremise(rule *compiledRule, b bindings, matched []*Edge) int {
+	next := -1
+	for k, premise := range rule.premises {
+		if matched[k] != nil {
+			continue
+		}
+		if b.resolve(premise.subject) != "" || b.resolve(premise.object) != "" {
+			return k
+		}
+		if next < 0 {
+			next = k
+		}
+	}
+	return next
+}
+
+// joinPremises cherche des arêtes compatibles avec les liaisons pour chaque prémisse non satisfaite,
+// et appelle emit pour chaque correspondance complète
+func (og *ontologyGraph) joinPremises(rule *compiledRule, b bindings, matched []*Edge, steps *int, emit func(bindings, []*Edge)) {
+	k := nextPremise(rule, b, matched)
+	if k < 0 {
+		emit(b, matched)
+		return
+	}
+
+	premise := rule.premises[k]
+	var candidates []*Edge
+	if subject := b.resolve(premise.subject); subject != "" {
+		candidates = og.outgoing.edges(subject, []string{premise.predicate})
+	} else if object := b.resolve(premise.object); object != "" {
+		candidates = og.incoming.edges(object, []string{premise.predicate})
+	} else {
+		candidates = og.byType[premise.predicate]
+	}
+	for _, candidate := range candidates {
+		if *steps >= maxJoinSteps {
+			break
+		}
+		*steps++
+		next := b.copy()
+		if !next.bind(premise.subject, candidate.Source) || !next.bind(premise.object, candidate.Target) {
+			continue
+		}
+		matched[k] = candidate
+		og.joinPremises(rule, next, matched, steps, emit)
+	}
+	matched[k] = nil
+}
+
+// InferredRelations retourne les relations déduites d'une ontologie, dans l'ordre de leur déduction
+func (g *Graph) InferredRelations(ontologyID string) ([]*models.Relation, error) {
+	graphs := g.graphs([]string{ontologyID})
+	if len(graphs) == 0 {
+		return nil, fmt.Errorf("ontology %s not found", ontologyID)
+	}
+
+	relations := []*models.Relation{}
+	for _, edge := range g.OntologyEdges(ontologyID) {
+		if edge.Inferred() {
+			relations = append(relations, edge.Relation)
+		}
+	}
+	return relations, nil
+}
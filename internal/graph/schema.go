@@ -0,0 +1,221 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+	"github.com/chrlesur/ontology-server/internal/storage"
+)
+
+// maxInferredEdges borne le nombre de relations déduites par ontologie, la fermeture
+// transitive d'un grand graphe pouvant être quadratique
+const maxInferredEdges = 50000
+
+// Préfixes des règles attribuées aux relations déduites des propriétés des types de relation
+const (
+	RuleInverseOf  = "inverse_of"
+	RuleSymmetric  = "symmetric"
+	RuleTransitive = "transitive"
+)
+
+// Inferred indique que l'arête a été déduite et non déclarée dans l'ontologie
+func (e *Edge) Inferred() bool {
+	return e.Relation.Inference != nil
+}
+
+// relationSchema regroupe les propriétés déclarées des types de relation d'une ontologie
+type relationSchema struct {
+	inverses   map[string][]string
+	symmetric  map[string]bool
+	transitive map[string]bool
+}
+
+// newRelationSchema indexe les déclarations ; une relation inverse vaut dans les deux sens
+func newRelationSchema(types []*models.RelationType) relationSchema {
+	schema := relationSchema{
+		inverses:   make(map[string][]string),
+		symmetric:  make(map[string]bool),
+		transitive: make(map[string]bool),
+	}
+	addInverse := func(a, b string) {
+		for _, existing := range schema.inverses[a] {
+			if existing == b {
+				return
+			}
+		}
+		schema.inverses[a] = append(schema.inverses[a], b)
+	}
+	for _, t := range types {
+		if t.InverseOf != "" {
+			addInverse(t.Name, t.InverseOf)
+			addInverse(t.InverseOf, t.Name)
+		}
+		schema.symmetric[t.Name] = schema.symmetric[t.Name] || t.Symmetric
+		schema.transitive[t.Name] = schema.transitive[t.Name] || t.Transitive
+	}
+	return schema
+}
+
+// empty indique qu'aucune propriété ne permet de déduire de relation
+func (s relationSchema) empty() bool {
+	return len(s.inverses) == 0 && len(s.symmetric) == 0 && len(s.transitive) == 0
+}
+
+// edgeKey identifie une arête par ses extrémités et son type
+func edgeKey(source, relationType, target string) string {
+	return source + "\x00" + relationType + "\x00" + target
+}
+
+// inferRelations ajoute au graphe les relations déduites des propriétés des types de relation
+// (inverse, symétrique, transitive) et des règles de l'ontologie, jusqu'à ce qu'aucune nouvelle
+// relation ne puisse être déduite
+func (og *ontologyGraph) inferRelations() {
+	schema := newRelationSchema(og.ontology.RelationTypes)
+	rules := compileRules(og.ontology.Rules)
+	if schema.empty() && len(rules) == 0 {
+		return
+	}
+
+	known := make(map[string]bool)
+	var pending []*Edge
+	for _, edges := range og.byType {
+		for _, edge := range edges {
+			known[edgeKey(edge.Source, edge.Type, edge.Target)] = true
+			pending = append(pending, edge)
+		}
+	}
+	// Traiter les relations déclarées dans leur ordre d'origine pour un résultat reproductible
+	sortEdges(pending)
+
+	next := len(og.ontology.Relations)
+	steps := 0
+	infer := func(source, relationType, target, rule string, premises ...*Edge) {
+		key := edgeKey(source, relationType, target)
+		if source == target || known[key] || next-len(og.ontology.Relations) >= maxInferredEdges {
+			return
+		}
+		known[key] = true
+
+		inference := &models.Inference{Rule: rule}
+		for _, premise := range premises {
+			inference.Premises = append(inference.Premises, premise.Relation)
+		}
+		relation := &models.Relation{
+			Source:    og.labels[source],
+			Type:      relationType,
+			Target:    og.labels[target],
+			Inference: inference,
+		}
+		if len(premises) == 1 {
+			relation.Description = premises[0].Relation.Description
+		}
+		// Une conclusion peut nommer un élément absent des relations déclarées
+		for _, id := range []string{source, target} {
+			if _, exists := og.labels[id]; !exists {
+				og.labels[id] = id
+			}
+		}
+		edge := &Edge{
+			OntologyID: og.ontology.ID,
+			Source:     source,
+			Target:     target,
+			Type:       relationType,
+			Relation:   relation,
+			index:      next,
+		}
+		next++
+		og.addEdge(edge)
+		pending = append(pending, edge)
+	}
+
+	for len(pending) > 0 {
+		edge := pending[0]
+		pending = pending[1:]
+
+		for _, inverse := range schema.inverses[edge.Type] {
+			infer(edge.Target, inverse, edge.Source, fmt.Sprintf("%s:%s", RuleInverseOf, edge.Type), edge)
+		}
+		if schema.symmetric[edge.Type] {
+			infer(edge.Target, edge.Type, edge.Source, fmt.Sprintf("%s:%s", RuleSymmetric, edge.Type), edge)
+		}
+		if schema.transitive[edge.Type] {
+			for _, after := range og.outgoing.edges(edge.Target, []string{edge.Type}) {
+				infer(edge.Source, edge.Type, after.Target, fmt.Sprintf("%s:%s", RuleTransitive, edge.Type), edge, after)
+			}
+			for _, before := range og.incoming.edges(edge.Source, []string{edge.Type}) {
+				infer(before.Source, edge.Type, edge.Target, fmt.Sprintf("%s:%s", RuleTransitive, edge.Type), before, edge)
+			}
+		}
+		// Les conclusions sont ajoutées après la jointure, qui parcourt les index du graphe
+		for _, d := range og.applyRules(rules, edge, &steps) {
+			infer(d.source, d.rule.conclusion.predicate, d.target, fmt.Sprintf("%s:%s", RulePrefix, d.rule.name), d.premises...)
+		}
+	}
+}
+
+// SchemaViolation signale une relation dont une extrémité ne respecte pas le domaine ou l'image de son type
+type SchemaViolation struct {
+	Relation *models.Relation `json:"relation"`
+	Reason   string           `json:"reason"`
+}
+
+// RelationTypes retourne les déclarations des types de relation d'une ontologie et les relations
+// déclarées qui ne respectent pas leur domaine ou leur image
+func (g *Graph) RelationTypes(ontologyID string) ([]*models.RelationType, []SchemaViolation, error) {
+	graphs := g.graphs([]string{ontologyID})
+	if len(graphs) == 0 {
+		return nil, nil, fmt.Errorf("ontology %s not found", ontologyID)
+	}
+	og := graphs[0]
+
+	declared := make(map[string]*models.RelationType)
+	for _, t := range og.ontology.RelationTypes {
+		declared[t.Name] = t
+	}
+	hierarchy := g.storage.TypeHierarchy(og.ontology)
+	violations := []SchemaViolation{}
+	for _, relation := range og.ontology.Relations {
+		t, exists := declared[relation.Type]
+		if !exists {
+			continue
+		}
+		source, target := storage.RelationEndpoints(relation)
+		if reason := og.typeMismatch(hierarchy, source, t.Domain, "source"); reason != "" {
+			violations = append(violations, SchemaViolation{Relation: relation, Reason: reason})
+		}
+		if reason := og.typeMismatch(hierarchy, target, t.Range, "target"); reason != "" {
+			violations = append(violations, SchemaViolation{Relation: relation, Reason: reason})
+		}
+	}
+
+	types := og.ontology.RelationTypes
+	if types == nil {
+		types = []*models.RelationType{}
+	}
+	return types, violations, nil
+}
+
+// typeMismatch explique pourquoi une extrémité n'a pas le type attendu, ou retourne une chaîne vide.
+// Les extrémités qui ne sont pas des éléments déclarés, dont le type est inconnu, ne sont pas signalées.
+// Les sous-types du type attendu le satisfont, comme dans le filtre par type de la recherche.
+func (og *ontologyGraph) typeMismatch(hierarchy *storage.TypeHierarchy, name, expected, role string) string {
+	if expected == "" {
+		return ""
+	}
+	element, exists := og.elements[CanonicalID(name)]
+	if !exists {
+		return ""
+	}
+	accepted := make(map[string]bool)
+	for _, key := range hierarchy.Expand([]string{expected}) {
+		accepted[key] = true
+	}
+	// Un type combiné "A/B" satisfait A comme B
+	for _, t := range strings.Split(element.Type, "/") {
+		if accepted[storage.NormalizeType(t)] {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%s %s has type %q, expected %q", role, name, element.Type, expected)
+}
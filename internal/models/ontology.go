@@ -12,6 +12,7 @@ type OntologyElement struct {
 	Positions    []int
 	Description  string
 	Contexts     []JSONContext
+	AltLabels    []string // Libellés alternatifs indexés avec Name et OriginalName
 }
 
 // Relation représente une relation entre deux éléments de l'ontologie
@@ -20,6 +21,48 @@ type Relation struct {
 	Type        string
 	Target      string
 	Description string
+	// SourceID et TargetID sont les noms des éléments auxquels les extrémités ont été rattachées au chargement,
+	// vides pour une extrémité non résolue
+	SourceID string `json:",omitempty"`
+	TargetID string `json:",omitempty"`
+	// Inference est renseignée pour une relation déduite des relations déclarées
+	Inference *Inference `json:",omitempty"`
+}
+
+// Inference explique comment une relation a été déduite
+type Inference struct {
+	// Rule désigne la propriété ou la règle appliquée, par exemple "inverse_of:contient"
+	Rule     string      `json:"rule"`
+	Premises []*Relation `json:"premises"`
+}
+
+// UnresolvedEndpoint signale une extrémité de relation qui n'a pu être rattachée à aucun élément
+type UnresolvedEndpoint struct {
+	// Relation est la position de la relation dans l'ontologie
+	Relation int    `json:"relation"`
+	Endpoint string `json:"endpoint"` // source ou target
+	Name     string `json:"name"`
+	// Candidates sont les éléments les plus proches, lorsque la correspondance est ambiguë ou trop faible
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// Rule est une règle d'inférence de la forme "A emploie B => B travaille_pour A"
+type Rule struct {
+	Name string `json:"name"`
+	// Rule contient les prémisses, séparées par des virgules, puis "=>" et la conclusion
+	Rule string `json:"rule"`
+}
+
+// RelationType déclare les propriétés d'un type de relation d'une ontologie
+type RelationType struct {
+	Name string `json:"name"`
+	// InverseOf est le type de relation inverse : A contient B implique B est_partie_de A
+	InverseOf  string `json:"inverse_of,omitempty"`
+	Symmetric  bool   `json:"symmetric,omitempty"`
+	Transitive bool   `json:"transitive,omitempty"`
+	// Domain et Range sont les types d'éléments attendus pour la source et la cible
+	Domain string `json:"domain,omitempty"`
+	Range  string `json:"range,omitempty"`
 }
 
 // SourceMetadata représente les métadonnées du fichier source
@@ -49,4 +92,12 @@ type Ontology struct {
 	Elements   []*OntologyElement
 	Relations  []*Relation
 	Source     *SourceMetadata
+	// RelationTypes déclare les propriétés des types de relation de l'ontologie
+	RelationTypes []*RelationType `json:",omitempty"`
+	// Rules sont les règles d'inférence évaluées sur les relations de l'ontologie
+	Rules []*Rule `json:",omitempty"`
+	// TypeParents déclare la taxonomie des types d'éléments : chaque type est associé à son type parent
+	TypeParents map[string]string `json:",omitempty"`
+	// UnresolvedEndpoints liste les extrémités de relation qui ne correspondent à aucun élément
+	UnresolvedEndpoints []*UnresolvedEndpoint `json:",omitempty"`
 }
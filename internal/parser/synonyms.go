@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseSynonymsTSV parses a synonyms file where each line is a set of
+// tab-separated equivalent terms. Empty lines and lines starting with "#" are ignored.
+func ParseSynonymsTSV(filename string) ([][]string, error) {
+	log.Info(fmt.Sprintf("Starting to parse synonyms file: %s", filename))
+
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to open file: %v", err))
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	var sets [][]string
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var terms []string
+		for _, term := range strings.Split(line, "\t") {
+			term = strings.TrimSpace(term)
+			if term != "" {
+				terms = append(terms, term)
+			}
+		}
+		if len(terms) < 2 {
+			log.Warning(fmt.Sprintf("Skipping synonym set with less than two terms on line %d", lineNumber))
+			continue
+		}
+		sets = append(sets, terms)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Error(fmt.Sprintf("Error reading synonyms file: %v", err))
+		return nil, fmt.Errorf("error reading synonyms file: %w", err)
+	}
+
+	log.Info(fmt.Sprintf("Finished parsing synonyms file. Found %d sets.", len(sets)))
+	return sets, nil
+}
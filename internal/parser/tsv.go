@@ -80,12 +80,19 @@ func ParseTSV(filename string) ([]models.OntologyElement, []models.Relation, err
 				}
 			}
 
+			// Cinquième colonne optionnelle : libellés alternatifs séparés par "|"
+			var altLabels []string
+			if len(record) > 4 {
+				altLabels = parseAltLabels(record[4])
+			}
+
 			element := models.OntologyElement{
 				Name:        name,
 				Type:        elemType,
 				Description: description,
 				Positions:   positions,
 				Contexts:    []models.JSONContext{},
+				AltLabels:   altLabels,
 			}
 			elements = append(elements, element)
 			elementMap[name] = &element
@@ -117,6 +124,17 @@ func ParseTSV(filename string) ([]models.OntologyElement, []models.Relation, err
 	return elements, relations, nil
 }
 
+func parseAltLabels(labelsStr string) []string {
+	var labels []string
+	for _, label := range strings.Split(labelsStr, "|") {
+		label = strings.TrimSpace(label)
+		if label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
 func parsePositions(positionsStr string) ([]int, error) {
 	positionsStr = strings.TrimSpace(positionsStr)
 	if positionsStr == "" {
@@ -0,0 +1,71 @@
+// Package regexlimit borne la taille et la complexité des expressions régulières fournies par les clients,
+// pour les recherches par motif comme pour les conditions =~ des requêtes de graphe.
+package regexlimit
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+)
+
+// Limites appliquées aux motifs pour protéger le serveur
+const (
+	MaxLength = 200
+	// MaxNodes borne le nombre de nœuds de l'arbre syntaxique du motif
+	MaxNodes = 100
+	// MaxRepeat borne les répétitions explicites ({n,m})
+	MaxRepeat = 100
+	// MaxInstructions borne la taille du programme compilé
+	MaxInstructions = 2000
+)
+
+// Check vérifie qu'un motif reste dans les limites de taille et de complexité
+func Check(pattern string) error {
+	if len([]rune(pattern)) > MaxLength {
+		return fmt.Errorf("pattern longer than %d characters", MaxLength)
+	}
+
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return err
+	}
+
+	nodes := 0
+	var walk func(re *syntax.Regexp) error
+	walk = func(re *syntax.Regexp) error {
+		nodes++
+		if nodes > MaxNodes {
+			return fmt.Errorf("pattern has more than %d elements", MaxNodes)
+		}
+		if re.Op == syntax.OpRepeat && (re.Min > MaxRepeat || re.Max > MaxRepeat) {
+			return fmt.Errorf("repetition count above %d", MaxRepeat)
+		}
+		for _, sub := range re.Sub {
+			if err := walk(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(parsed); err != nil {
+		return err
+	}
+
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return err
+	}
+	if len(prog.Inst) > MaxInstructions {
+		return fmt.Errorf("pattern too complex")
+	}
+	return nil
+}
+
+// Compile vérifie les limites d'un motif puis le compile.
+// Le moteur d'expressions régulières de Go garantit un temps d'exécution linéaire.
+func Compile(pattern string) (*regexp.Regexp, error) {
+	if err := Check(pattern); err != nil {
+		return nil, err
+	}
+	return regexp.Compile(pattern)
+}
@@ -0,0 +1,66 @@
+package regexlimit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		// wantErr est une partie du message d'erreur attendu, vide si le motif est accepté
+		wantErr string
+	}{
+		{"simple", "^Service_.*Public$", ""},
+		{"invalid syntax", "Agent_(", "missing closing )"},
+
+		{"length at limit", strings.Repeat("a", MaxLength), ""},
+		{"length above limit", strings.Repeat("a", MaxLength+1), "longer than"},
+		// La longueur se compte en caractères et non en octets
+		{"accented length at limit", strings.Repeat("é", MaxLength), ""},
+
+		// Chaque groupe (a) compte deux nœuds, sous une concaténation
+		{"nodes under limit", strings.Repeat("(a)", (MaxNodes-1)/2), ""},
+		{"nodes above limit", strings.Repeat("(a)", MaxNodes/2), "more than"},
+
+		{"repeat at limit", "a{100}", ""},
+		{"repeat above limit", "a{101}", "repetition count"},
+		{"repeat range at limit", "a{2,100}", ""},
+		{"repeat range above limit", "a{2,101}", "repetition count"},
+
+		// Chaque a{2,100} se compile en près de 200 instructions
+		{"instructions under limit", strings.Repeat("a{2,100}", 10), ""},
+		{"nested instructions under limit", "(?:a{2,100}){10}", ""},
+		{"instructions above limit", strings.Repeat("a{2,100}", 11), "too complex"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Check(test.pattern)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected %q to be accepted, got %v", test.pattern, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	re, err := Compile("(?i)^service_")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if !re.MatchString("Service_Public") {
+		t.Errorf("Expected the compiled pattern to match Service_Public")
+	}
+
+	if re, err := Compile("a{101}"); err == nil || re != nil {
+		t.Errorf("Expected a pattern above the limits to be rejected, got %v", re)
+	}
+}
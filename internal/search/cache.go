@@ -0,0 +1,272 @@
+package search
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Limites par défaut du cache de résultats
+const (
+	defaultCacheEntries = 500
+	defaultCacheBytes   = 32 << 20
+)
+
+// CacheStats expose l'état et les compteurs du cache de résultats
+type CacheStats struct {
+	Entries       int    `json:"entries"`
+	Bytes         int    `json:"bytes"`
+	MaxEntries    int    `json:"max_entries"`
+	MaxBytes      int    `json:"max_bytes"`
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
+}
+
+// cacheEntry est une réponse mise en cache avec les ontologies dont elle dépend
+type cacheEntry struct {
+	key      string
+	response *SearchResponse
+	size     int
+	// ontologyIDs restreint la dépendance aux ontologies filtrées ; vide, la réponse dépend de toutes
+	ontologyIDs []string
+}
+
+// ResultCache est un cache LRU des réponses de recherche, borné en nombre d'entrées et en octets.
+// Une entrée est invalidée dès qu'une ontologie qu'elle a parcourue est ajoutée, modifiée ou supprimée.
+type ResultCache struct {
+	mutex      sync.Mutex
+	maxEntries int
+	maxBytes   int
+	bytes      int
+	order      *list.List
+	entries    map[string]*list.Element
+	// byOntology indexe les clés des entrées filtrées par ontologie, global celles qui dépendent de toutes
+	byOntology map[string]map[string]bool
+	global     map[string]bool
+	// generation est incrémentée à chaque invalidation : une réponse calculée avant une invalidation
+	// n'est pas mise en cache, faute de pouvoir savoir si elle a lu l'ontologie modifiée
+	generation uint64
+
+	hits          uint64
+	misses        uint64
+	evictions     uint64
+	invalidations uint64
+}
+
+// NewResultCache crée un cache de résultats ; une limite nulle ou négative prend la valeur par défaut
+func NewResultCache(maxEntries, maxBytes int) *ResultCache {
+	rc := &ResultCache{
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		byOntology: make(map[string]map[string]bool),
+		global:     make(map[string]bool),
+	}
+	rc.Resize(maxEntries, maxBytes)
+	return rc
+}
+
+// Resize change les limites du cache et évince les entrées en excès
+func (rc *ResultCache) Resize(maxEntries, maxBytes int) {
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheEntries
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultCacheBytes
+	}
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.maxEntries = maxEntries
+	rc.maxBytes = maxBytes
+	rc.evict()
+}
+
+// cacheKey construit la clé d'une recherche à partir de la requête normalisée et des filtres triés
+func cacheKey(opts SearchOptions, synonymsVersion uint64) string {
+	sorted := func(values []string) string {
+		values = append([]string(nil), values...)
+		sort.Strings(values)
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprintf("q=%s|o=%s|t=%s|f=%s|r=%s|cs=%d|facets=%t|ctx=%t|explain=%t|mode=%s|syn=%d",
+		opts.Query, sorted(opts.OntologyIDs), sorted(opts.ElementTypes), sorted(opts.FileIDs),
+		sorted(opts.RelationTypes), opts.ContextSize, opts.WithFacets, opts.SearchContexts, opts.Explain, opts.Mode, synonymsVersion)
+}
+
+// Get retourne la réponse associée à la clé et la marque comme récemment utilisée.
+// La génération retournée doit être transmise à Put pour mettre en cache la réponse calculée en cas d'absence.
+func (rc *ResultCache) Get(key string) (*SearchResponse, uint64, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	element, exists := rc.entries[key]
+	if !exists {
+		rc.misses++
+		return nil, rc.generation, false
+	}
+	rc.hits++
+	rc.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).response, rc.generation, true
+}
+
+// Put met une réponse en cache. Les réponses partielles, celles qui dépassent à elles seules
+// la limite en octets et celles calculées avant une invalidation postérieure à Get ne sont pas conservées.
+func (rc *ResultCache) Put(key string, opts SearchOptions, response *SearchResponse, generation uint64) {
+	if response.Partial {
+		return
+	}
+
+	entry := &cacheEntry{key: key, response: response, size: len(key) + responseSize(response)}
+	// Les facettes d'ontologie parcourent toutes les ontologies, même non sélectionnées, et les scores
+	// sémantiques dépendent des fréquences documentaires calculées sur tout le corpus
+	if !opts.WithFacets && opts.Mode != SearchModeSemantic && opts.Mode != SearchModeHybrid {
+		entry.ontologyIDs = append([]string(nil), opts.OntologyIDs...)
+	}
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	if generation != rc.generation || entry.size > rc.maxBytes {
+		return
+	}
+	if existing, exists := rc.entries[key]; exists {
+		rc.remove(existing)
+	}
+
+	rc.entries[key] = rc.order.PushFront(entry)
+	rc.bytes += entry.size
+	if len(entry.ontologyIDs) == 0 {
+		rc.global[key] = true
+	}
+	for _, id := range entry.ontologyIDs {
+		if rc.byOntology[id] == nil {
+			rc.byOntology[id] = make(map[string]bool)
+		}
+		rc.byOntology[id][key] = true
+	}
+	rc.evict()
+}
+
+// Invalidate supprime les entrées qui dépendent de l'ontologie indiquée
+func (rc *ResultCache) Invalidate(ontologyID string) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	rc.generation++
+
+	keys := make([]string, 0, len(rc.global)+len(rc.byOntology[ontologyID]))
+	for key := range rc.global {
+		keys = append(keys, key)
+	}
+	for key := range rc.byOntology[ontologyID] {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		if element, exists := rc.entries[key]; exists {
+			rc.remove(element)
+			rc.invalidations++
+		}
+	}
+}
+
+// Clear vide le cache sans remettre les compteurs à zéro
+func (rc *ResultCache) Clear() {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	rc.generation++
+	rc.invalidations += uint64(len(rc.entries))
+	rc.order.Init()
+	rc.entries = make(map[string]*list.Element)
+	rc.byOntology = make(map[string]map[string]bool)
+	rc.global = make(map[string]bool)
+	rc.bytes = 0
+}
+
+// Stats retourne l'état courant du cache
+func (rc *ResultCache) Stats() CacheStats {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return CacheStats{
+		Entries:       len(rc.entries),
+		Bytes:         rc.bytes,
+		MaxEntries:    rc.maxEntries,
+		MaxBytes:      rc.maxBytes,
+		Hits:          rc.hits,
+		Misses:        rc.misses,
+		Evictions:     rc.evictions,
+		Invalidations: rc.invalidations,
+	}
+}
+
+// Estimations de taille des éléments d'une réponse, en octets
+const (
+	resultOverhead  = 128
+	contextOverhead = 64
+	wordOverhead    = 16
+)
+
+// responseSize estime l'empreinte mémoire d'une réponse à partir de ses chaînes, sans la sérialiser.
+// Les métadonnées de source, partagées avec l'ontologie stockée, ne sont pas comptées.
+func responseSize(response *SearchResponse) int {
+	size := 0
+	for _, result := range response.Results {
+		size += resultOverhead + len(result.OntologyID) + len(result.ElementName) + len(result.ElementType) +
+			len(result.Description) + len(result.Context)
+		for _, ctx := range result.Contexts {
+			size += contextOverhead + len(ctx.FileID) + len(ctx.Element)
+			for _, words := range [][]string{ctx.Before, ctx.After} {
+				for _, word := range words {
+					size += wordOverhead + len(word)
+				}
+			}
+		}
+		for _, match := range result.ContextMatches {
+			size += contextOverhead + len(match.FileID) + len(match.Snippet) + contextOverhead*len(match.Highlights)
+		}
+		if result.Explanation != nil {
+			size += resultOverhead * (1 + len(result.Explanation.Fields))
+		}
+	}
+	if response.Facets != nil {
+		for _, counts := range [][]FacetCount{response.Facets.ElementTypes, response.Facets.Ontologies,
+			response.Facets.Files, response.Facets.RelationTypes} {
+			for _, count := range counts {
+				size += contextOverhead + len(count.Value) + len(count.Label)
+			}
+		}
+	}
+	return size
+}
+
+// evict retire les entrées les moins récemment utilisées tant qu'une limite est dépassée
+func (rc *ResultCache) evict() {
+	for len(rc.entries) > rc.maxEntries || rc.bytes > rc.maxBytes {
+		oldest := rc.order.Back()
+		if oldest == nil {
+			return
+		}
+		rc.remove(oldest)
+		rc.evictions++
+	}
+}
+
+// remove retire une entrée du cache et de ses index
+func (rc *ResultCache) remove(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+	rc.order.Remove(element)
+	delete(rc.entries, entry.key)
+	delete(rc.global, entry.key)
+	for _, id := range entry.ontologyIDs {
+		delete(rc.byOntology[id], entry.key)
+		if len(rc.byOntology[id]) == 0 {
+			delete(rc.byOntology, id)
+		}
+	}
+	rc.bytes -= entry.size
+}
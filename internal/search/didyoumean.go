@@ -0,0 +1,181 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/agnivade/levenshtein"
+)
+
+// maxAlternativesPerTerm borne le nombre de corrections retenues pour chaque terme inconnu
+const maxAlternativesPerTerm = 3
+
+// termCandidate est une correction possible pour un terme de la requête
+type termCandidate struct {
+	word  string
+	score float64
+}
+
+// phoneticRules regroupe, dans l'ordre d'application, les graphies françaises qui se prononcent de la même façon
+var phoneticRules = []struct{ from, to string }{
+	{"tion", "sion"},
+	{"eau", "o"}, {"au", "o"},
+	{"ph", "f"}, {"th", "t"}, {"qu", "k"}, {"gu", "g"}, {"ch", "sh"},
+	{"ain", "in"}, {"ein", "in"}, {"im", "in"}, {"ym", "in"}, {"yn", "in"},
+	{"am", "an"}, {"em", "an"}, {"en", "an"},
+	{"om", "on"},
+	{"ai", "e"}, {"ei", "e"},
+	{"ce", "se"}, {"ci", "si"}, {"cy", "si"},
+	{"ge", "je"}, {"gi", "ji"},
+	{"c", "k"}, {"q", "k"},
+	{"w", "v"}, {"y", "i"}, {"z", "s"},
+	{"h", ""},
+}
+
+// phoneticKey calcule une clé phonétique approximative d'un mot français,
+// de sorte que "fonctionaire" et "fonctionnaire" partagent la même clé
+func phoneticKey(word string) string {
+	key := foldText(word)
+	key = strings.TrimRight(key, "sxtd")
+	for _, rule := range phoneticRules {
+		key = strings.ReplaceAll(key, rule.from, rule.to)
+	}
+	key = strings.TrimSuffix(key, "e")
+
+	// Fusionner les lettres doublées
+	var builder strings.Builder
+	var previous rune
+	for _, r := range key {
+		if r != previous {
+			builder.WriteRune(r)
+		}
+		previous = r
+	}
+	return builder.String()
+}
+
+// maxEditDistance retourne la distance d'édition tolérée selon la longueur du terme
+func maxEditDistance(term string) int {
+	switch length := len([]rune(term)); {
+	case length <= 4:
+		return 1
+	case length <= 8:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// correctTerm cherche dans le vocabulaire les mots proches d'un terme, par distance d'édition ou par clé phonétique.
+// Les clés phonétiques du vocabulaire sont calculées à la construction des arbres de complétion.
+func correctTerm(term string, vocabulary map[string]int, phonetics map[string]string) []termCandidate {
+	key := phoneticKey(term)
+	maxDistance := maxEditDistance(term)
+
+	var candidates []termCandidate
+	for word, frequency := range vocabulary {
+		distance := levenshtein.ComputeDistance(term, word)
+		phonetic := key != "" && phonetics[word] == key
+		if distance > maxDistance && !phonetic {
+			continue
+		}
+
+		score := 1 - float64(distance)/float64(max(len([]rune(term)), len([]rune(word))))
+		if phonetic {
+			score += 0.5
+		}
+		score += 0.05 * math.Log1p(float64(frequency))
+		candidates = append(candidates, termCandidate{word: word, score: score})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].word < candidates[j].word
+	})
+	if len(candidates) > maxAlternativesPerTerm {
+		candidates = candidates[:maxAlternativesPerTerm]
+	}
+	return candidates
+}
+
+// DidYouMean propose des requêtes alternatives construites à partir du vocabulaire des ontologies,
+// en remplaçant chaque terme inconnu par les mots les plus proches
+func (se *SearchEngine) DidYouMean(query string, ontologyIDs []string, limit int) []string {
+	terms := strings.Fields(normalizeSuggestKey(query))
+	if len(terms) == 0 || limit <= 0 {
+		return []string{}
+	}
+
+	se.suggestions.refresh(se.Storage.ListOntologies())
+
+	se.suggestions.mutex.RLock()
+	vocabulary := make(map[string]int)
+	phonetics := make(map[string]string)
+	for id, trie := range se.suggestions.tries {
+		if !matchesAnyValue([]string{id}, ontologyIDs) {
+			continue
+		}
+		for word, frequency := range trie.words {
+			vocabulary[word] += frequency
+			phonetics[word] = trie.phonetics[word]
+		}
+	}
+	se.suggestions.mutex.RUnlock()
+
+	// Pour chaque terme, la liste des remplacements possibles, le terme lui-même s'il est connu
+	alternatives := make([][]termCandidate, len(terms))
+	corrected := false
+	for i, term := range terms {
+		if _, known := vocabulary[term]; known {
+			alternatives[i] = []termCandidate{{word: term}}
+			continue
+		}
+		alternatives[i] = correctTerm(term, vocabulary, phonetics)
+		if len(alternatives[i]) == 0 {
+			// Terme sans correction possible : on le conserve tel quel
+			alternatives[i] = []termCandidate{{word: term}}
+			continue
+		}
+		corrected = true
+	}
+	if !corrected {
+		return []string{}
+	}
+
+	// La première proposition retient la meilleure correction de chaque terme,
+	// les suivantes font varier un terme à la fois
+	seen := make(map[string]bool)
+	var queries []string
+	addQuery := func(words []string) {
+		q := strings.Join(words, " ")
+		if q != strings.Join(terms, " ") && !seen[q] {
+			seen[q] = true
+			queries = append(queries, q)
+		}
+	}
+
+	bestWords := make([]string, len(terms))
+	for i, candidates := range alternatives {
+		bestWords[i] = candidates[0].word
+	}
+	addQuery(bestWords)
+
+	for rank := 1; rank < maxAlternativesPerTerm; rank++ {
+		for i, candidates := range alternatives {
+			if rank >= len(candidates) {
+				continue
+			}
+			words := append([]string(nil), bestWords...)
+			words[i] = candidates[rank].word
+			addQuery(words)
+		}
+	}
+
+	if len(queries) > limit {
+		queries = queries[:limit]
+	}
+	return queries
+}
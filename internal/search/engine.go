@@ -1,30 +1,65 @@
 package search
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/agnivade/levenshtein"
+	"github.com/chrlesur/ontology-server/internal/graph"
 	"github.com/chrlesur/ontology-server/internal/logger"
 	"github.com/chrlesur/ontology-server/internal/models"
 	"github.com/chrlesur/ontology-server/internal/storage"
 )
 
+// relevanceThreshold est le score en dessous duquel un élément n'est pas retenu
+const relevanceThreshold = 0.3
+
 // SearchEngine représente le moteur de recherche
 type SearchEngine struct {
 	Storage *storage.MemoryStorage
 	Logger  *logger.Logger
+	sources *sourceCache
+	// suggestions est l'index préfixe utilisé par l'autocomplétion
+	suggestions *SuggestIndex
+	// semantic contient les vecteurs TF-IDF des modes sémantique et hybride
+	semantic *SemanticIndex
+	// similarity conserve les profils utilisés pour les éléments similaires
+	similarity *similarityIndex
+	// Synonyms contient les ensembles de synonymes utilisés pour étendre les requêtes
+	Synonyms *SynonymDictionary
+	// Timeout borne la durée d'une recherche ; 0 désactive la limite
+	Timeout time.Duration
+	// Cache conserve les réponses récentes, invalidées à chaque modification des ontologies parcourues
+	Cache *ResultCache
+	// Graph indexe les relations des ontologies, partagé avec les requêtes de graphe de l'API
+	Graph *graph.Graph
 }
 
 // NewSearchEngine crée une nouvelle instance de SearchEngine
 func NewSearchEngine(storage *storage.MemoryStorage, logger *logger.Logger) *SearchEngine {
-	return &SearchEngine{
+	se := &SearchEngine{
 		Storage: storage,
 		Logger:  logger,
+		sources: newSourceCache(defaultSourceCacheBytes),
+
+		suggestions: NewSuggestIndex(),
+		semantic:    NewSemanticIndex(),
+		similarity:  &similarityIndex{},
+		Synonyms:    NewSynonymDictionary(),
+		Cache:       NewResultCache(0, 0),
+		Graph:       graph.New(storage),
 	}
+	storage.OnChange(se.Cache.Invalidate)
+	storage.OnChange(se.semantic.invalidate)
+	// Le graphe s'est abonné en premier : les profils sont oubliés une fois ses relations à jour
+	storage.OnChange(se.similarity.invalidate)
+	return se
 }
 
 // SearchResult représente un résultat de recherche
@@ -38,21 +73,174 @@ type SearchResult struct {
 	Relevance   float64
 	Contexts    []models.JSONContext
 	Source      *models.SourceMetadata
+	// Element est l'élément trouvé, dans l'ontologie OntologyID
+	Element *models.OntologyElement
+	// ContextMatches liste les contextes contenant la requête, avec les passages à mettre en évidence
+	ContextMatches []ContextMatch
+	// Explanation détaille le calcul de la pertinence lorsque SearchOptions.Explain est activé
+	Explanation *Explanation
+}
+
+// SearchOptions regroupe les critères d'une recherche. Chaque filtre accepte
+// plusieurs valeurs : un élément est retenu s'il correspond à l'une d'elles.
+type SearchOptions struct {
+	Query         string
+	OntologyIDs   []string
+	ElementTypes  []string
+	FileIDs       []string
+	RelationTypes []string
+	// ContextSize limite les contextes à ce nombre de mots avant et après l'élément ;
+	// 0 conserve les contextes tels qu'ils ont été stockés
+	ContextSize int
+	WithFacets  bool
+	// SearchContexts étend la recherche au texte entourant chaque occurrence (Before/After)
+	SearchContexts bool
+	// Explain joint à chaque résultat le détail du calcul de sa pertinence
+	Explain bool
+	// Mode choisit le calcul de pertinence : lexical (défaut), semantic ou hybrid
+	Mode string
+	// NoCache exécute la recherche sans lire ni alimenter le cache de résultats
+	NoCache bool
+}
+
+// SearchResponse regroupe les résultats d'une recherche et les informations qui les accompagnent
+type SearchResponse struct {
+	Results []SearchResult
+	Facets  *Facets
+	// Partial indique que la recherche a été interrompue (annulation ou délai dépassé)
+	// et que les résultats ne couvrent pas toutes les ontologies
+	Partial bool
 }
 
 // Search effectue une recherche dans les ontologies
 func (se *SearchEngine) Search(query string, ontologyID string, elementType string, contextSize int, fileID string) ([]SearchResult, error) {
-	se.Logger.Info(fmt.Sprintf("Starting search with query: %s, ontologyID: %s, elementType: %s, fileID: %s", query, ontologyID, elementType, fileID))
-	query = strings.ToLower(query)
+	response, err := se.SearchWithOptions(context.Background(), SearchOptions{
+		Query:        query,
+		OntologyIDs:  singleValue(ontologyID),
+		ElementTypes: singleValue(elementType),
+		FileIDs:      singleValue(fileID),
+		ContextSize:  contextSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+// SearchWithOptions effectue une recherche multi-filtres et calcule, si demandé, les décomptes par facette.
+// Les recherches par ontologie s'arrêtent dès que ctx est annulé ou que le délai Timeout est écoulé ;
+// les résultats déjà trouvés sont alors retournés avec Partial à true.
+func (se *SearchEngine) SearchWithOptions(ctx context.Context, opts SearchOptions) (*SearchResponse, error) {
+	opts.Query = normalizeQuery(opts)
+	if opts.NoCache {
+		return se.search(ctx, opts)
+	}
+	key := cacheKey(opts, se.Synonyms.Version())
+	cached, generation, ok := se.Cache.Get(key)
+	if ok {
+		se.Logger.Info(fmt.Sprintf("Search for %s served from cache", opts.Query))
+		return cached, nil
+	}
+
+	response, err := se.search(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	se.Cache.Put(key, opts, response, generation)
+	return response, nil
+}
+
+// SearchStream transmet les résultats d'une recherche à emit.
+// Triés, ils ne sont transmis qu'à la fin d'une recherche complète (éventuellement servie par le cache) ;
+// seul le mode non trié transmet chaque résultat dès qu'il a été évalué. La réponse retournée porte
+// les facettes et l'indicateur Partial, sans les résultats. Une erreur de emit interrompt la recherche.
+func (se *SearchEngine) SearchStream(ctx context.Context, opts SearchOptions, sorted bool, emit func(SearchResult) error) (*SearchResponse, error) {
+	if sorted {
+		response, err := se.SearchWithOptions(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range response.Results {
+			if err := emit(result); err != nil {
+				return nil, err
+			}
+		}
+		return &SearchResponse{Facets: response.Facets, Partial: response.Partial}, nil
+	}
+
+	opts.Query = normalizeQuery(opts)
+	return se.scan(ctx, opts, emit)
+}
+
+// normalizeQuery retourne la forme canonique de la requête, utilisée pour la recherche et la clé de cache
+func normalizeQuery(opts SearchOptions) string {
+	if isPatternMode(opts.Mode) {
+		// La casse et les espaces sont significatifs dans un motif
+		return strings.TrimSpace(opts.Query)
+	}
+	return strings.Join(strings.Fields(strings.ToLower(opts.Query)), " ")
+}
+
+// search exécute une recherche sans passer par le cache et trie les résultats par pertinence
+func (se *SearchEngine) search(ctx context.Context, opts SearchOptions) (*SearchResponse, error) {
 	var results []SearchResult
+	response, err := se.scan(ctx, opts, func(result SearchResult) error {
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortSearchResults(results)
+	response.Results = results
+	return response, nil
+}
+
+// scan parcourt les ontologies et transmet chaque résultat retenu à emit, dans l'ordre où il est évalué.
+// emit est toujours appelée depuis la même goroutine.
+func (se *SearchEngine) scan(ctx context.Context, opts SearchOptions, emit func(SearchResult) error) (*SearchResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if se.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, se.Timeout)
+		defer cancel()
+	}
+
+	var matcher *patternMatcher
+	if isPatternMode(opts.Mode) {
+		var err error
+		if matcher, err = compilePattern(opts.Mode, opts.Query); err != nil {
+			return nil, err
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, patternSearchTimeout)
+		defer cancel()
+	}
+
+	se.Logger.Info(fmt.Sprintf("Starting search with query: %s, ontologyIDs: %v, elementTypes: %v, fileIDs: %v, relationTypes: %v",
+		opts.Query, opts.OntologyIDs, opts.ElementTypes, opts.FileIDs, opts.RelationTypes))
+	query := strings.ToLower(opts.Query)
 	var wg sync.WaitGroup
+	// interrupted est positionné par une recherche par ontologie qui abandonne des éléments à l'annulation
+	var interrupted atomic.Bool
 	resultChan := make(chan SearchResult)
+	counter := newFacetCounter()
 
-	ontologies := se.Storage.ListOntologies()
+	var ontologies []*models.Ontology
+	var queryVector vector
+	if opts.Mode == SearchModeSemantic || opts.Mode == SearchModeHybrid {
+		// La recherche parcourt les ontologies listées pour l'index sémantique
+		ontologies = se.semantic.refresh(se.Storage.ListOntologies)
+		queryVector = se.semantic.queryVector(opts.Query)
+	} else {
+		ontologies = se.Storage.ListOntologies()
+	}
 	se.Logger.Info(fmt.Sprintf("Searching through %d ontologies", len(ontologies)))
 
 	for _, ontology := range ontologies {
-		if ontologyID != "" && ontology.ID != ontologyID {
+		// Les facettes d'ontologie doivent aussi compter les ontologies non sélectionnées
+		if !opts.WithFacets && !matchesAnyValue([]string{ontology.ID}, opts.OntologyIDs) {
 			continue
 		}
 
@@ -60,48 +248,127 @@ func (se *SearchEngine) Search(query string, ontologyID string, elementType stri
 		go func(onto *models.Ontology) {
 			defer wg.Done()
 			se.Logger.Info(fmt.Sprintf("Searching in ontology: %s (Elements: %d)", onto.ID, len(onto.Elements)))
+
+			var relationTypes map[string][]string
+			if opts.WithFacets || len(opts.RelationTypes) > 0 {
+				relationTypes = elementRelationTypes(onto)
+			}
+			threshold := modeThreshold(opts.Mode)
+			// Les fichiers source de l'ontologie sont lus une seule fois pour toute la requête
+			sources := se.sourceReader()
+			// Un type parent inclut ses sous-types dans la hiérarchie de types de l'ontologie
+			var elementTypes []string
+			if len(opts.ElementTypes) > 0 {
+				elementTypes = se.Storage.TypeHierarchy(onto).Expand(opts.ElementTypes)
+			}
+			ontologyMatch := matchesAnyValue([]string{onto.ID}, opts.OntologyIDs)
+			var expansions []string
+			if matcher == nil {
+				expansions = se.Synonyms.Expand(opts.Query, onto.ID)
+			}
+			if len(expansions) > 0 {
+				se.Logger.Info(fmt.Sprintf("Query %s expanded in ontology %s: %v", opts.Query, onto.ID, expansions))
+			}
+
 			for _, element := range onto.Elements {
+				if ctx.Err() != nil {
+					se.Logger.Warning(fmt.Sprintf("Search in ontology %s interrupted: %v", onto.ID, ctx.Err()))
+					interrupted.Store(true)
+					return
+				}
 				se.Logger.Info(fmt.Sprintf("Examining element: %s (Type: %s, Contexts: %d)", element.Name, element.Type, len(element.Contexts)))
 
-				if elementType != "" && element.Type != elementType {
+				var elementRelations []string
+				if relationTypes != nil {
+					elementRelations = relationTypes[storage.NormalizeElementName(element.Name)]
+				}
+				match := facetMatch{
+					ontology:    ontologyMatch,
+					elementType: matchesElementType(element.Type, elementTypes),
+					file:        matchesFileIDs(element, opts.FileIDs),
+					relation:    matchesAnyValue(elementRelations, opts.RelationTypes),
+				}
+				if !opts.WithFacets && !match.all() {
+					se.Logger.Info(fmt.Sprintf("Element %s skipped: does not match filters", element.Name))
 					continue
 				}
 
-				// Vérification du fileID
-				if fileID != "" {
-					matchesFileID := false
-					for _, context := range element.Contexts {
-						if context.FileID == fileID {
-							matchesFileID = true
-							break
+				var relevance, semantic float64
+				var fields lexicalFields
+				source, matchedQuery := MatchSourceLexical, opts.Query
+				if matcher != nil {
+					relevance, _ = matcher.match(element)
+					source = MatchSourcePattern
+				} else {
+					var lexical float64
+					lexical, fields = calculateRelevance(query, element)
+					for _, expansion := range expansions {
+						// Une correspondance obtenue par synonyme reste légèrement moins pertinente
+						expanded, expandedFields := calculateRelevance(strings.ToLower(expansion), element)
+						if expanded *= synonymPenalty; expanded > lexical {
+							lexical, fields, source, matchedQuery = expanded, expandedFields, MatchSourceSynonym, expansion
 						}
 					}
-					if !matchesFileID {
-						se.Logger.Info(fmt.Sprintf("Element %s skipped: does not match fileID %s", element.Name, fileID))
-						continue
+					if queryVector != nil {
+						semantic = cosine(queryVector, se.semantic.vector(element))
 					}
-				}
-
-				relevance := calculateRelevance(query, element)
-				if relevance > 0.3 {
-					context := extractContext(element, contextSize)
-					position := 0
-					if len(element.Positions) > 0 {
-						position = element.Positions[0]
+					relevance = blendRelevance(opts.Mode, lexical, semantic)
+					if semanticDominates(opts.Mode, lexical, semantic) {
+						source, matchedQuery = MatchSourceSemantic, opts.Query
 					}
-					result := SearchResult{
-						OntologyID:  onto.ID,
-						ElementName: element.Name,
-						ElementType: element.Type,
-						Description: element.Description,
-						Context:     context,
-						Position:    position,
-						Relevance:   relevance,
-						Contexts:    element.Contexts,
-						Source:      onto.Source,
+				}
+				contexts := element.Contexts
+				if opts.ContextSize > 0 && (opts.SearchContexts || relevance > threshold) {
+					contexts = sources.contextWindows(element.Contexts, onto.Source, opts.ContextSize)
+				}
+				var contextMatches []ContextMatch
+				if opts.SearchContexts && matcher == nil {
+					var contextRelevance float64
+					contextMatches, contextRelevance = matchContexts(opts.Query, contexts)
+					if contextRelevance > relevance {
+						relevance, source, matchedQuery = contextRelevance, MatchSourceContext, opts.Query
 					}
-					se.Logger.Info(fmt.Sprintf("Found relevant result: %s (Relevance: %.2f)", result.ElementName, result.Relevance))
-					resultChan <- result
+				}
+				if relevance <= threshold {
+					continue
+				}
+				if opts.WithFacets {
+					counter.add(onto, element, elementRelations, match)
+				}
+				if !match.all() {
+					continue
+				}
+
+				context := extractContext(contexts)
+				position := 0
+				if len(element.Positions) > 0 {
+					position = element.Positions[0]
+				}
+				result := SearchResult{
+					OntologyID:  onto.ID,
+					ElementName: element.Name,
+					ElementType: element.Type,
+					Description: element.Description,
+					Context:     context,
+					Position:    position,
+					Relevance:   relevance,
+					Contexts:    contexts,
+					Source:      onto.Source,
+					Element:     element,
+
+					ContextMatches: contextMatches,
+				}
+				if opts.Explain && matcher != nil {
+					result.Explanation = matcher.explain(opts, element, relevance)
+				} else if opts.Explain {
+					result.Explanation = explainResult(opts, element, fields, relevance, semantic, source, matchedQuery, contextMatches)
+				}
+				se.Logger.Info(fmt.Sprintf("Found relevant result: %s (Relevance: %.2f)", result.ElementName, result.Relevance))
+				select {
+				case resultChan <- result:
+				case <-ctx.Done():
+					interrupted.Store(true)
+					return
 				}
 			}
 		}(ontology)
@@ -112,14 +379,44 @@ func (se *SearchEngine) Search(query string, ontologyID string, elementType stri
 		close(resultChan)
 	}()
 
+	count := 0
+	var emitErr error
 	for result := range resultChan {
-		results = append(results, result)
+		if emitErr != nil {
+			// Vider le canal jusqu'à l'arrêt des recherches par ontologie
+			continue
+		}
+		if emitErr = emit(result); emitErr != nil {
+			cancel()
+			continue
+		}
+		count++
+	}
+	if emitErr != nil {
+		se.Logger.Warning(fmt.Sprintf("Search stopped after %d results: %v", count, emitErr))
+		return nil, emitErr
 	}
 
-	sortSearchResults(results)
+	// Une recherche achevée juste avant l'échéance n'a rien abandonné et reste complète
+	response := &SearchResponse{Partial: interrupted.Load()}
+	if opts.WithFacets {
+		response.Facets = counter.facets()
+	}
+
+	if response.Partial {
+		se.Logger.Warning(fmt.Sprintf("Search interrupted (%v). Returning %d partial results.", ctx.Err(), count))
+	} else {
+		se.Logger.Info(fmt.Sprintf("Search completed. Found %d results.", count))
+	}
+	return response, nil
+}
 
-	se.Logger.Info(fmt.Sprintf("Search completed. Found %d results.", len(results)))
-	return results, nil
+// singleValue convertit un filtre optionnel à valeur unique en liste de valeurs
+func singleValue(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
 }
 
 // sortSearchResults trie les résultats de recherche par pertinence décroissante
@@ -129,49 +426,70 @@ func sortSearchResults(results []SearchResult) {
 	})
 }
 
-// calculateRelevance calcule la pertinence d'un élément par rapport à la requête
-func calculateRelevance(query string, element *models.OntologyElement) float64 {
-	relevance := 0.0
+// Poids des champs dans le score lexical
+const (
+	nameWeight        = 0.6
+	typeWeight        = 0.3
+	descriptionWeight = 0.1
+	// synonymPenalty réduit le score d'une correspondance obtenue par synonyme
+	synonymPenalty = 0.95
+)
 
-	nameRelevance := fuzzyMatch(query, element.Name)
-	relevance += nameRelevance * 0.6
+// lexicalFields détaille l'apport du nom, du type et de la description au score lexical
+type lexicalFields [3]FieldContribution
 
-	typeRelevance := fuzzyMatch(query, element.Type)
-	relevance += typeRelevance * 0.3
+// calculateRelevance calcule la pertinence d'un élément par rapport à la requête, avec l'apport de chaque champ
+func calculateRelevance(query string, element *models.OntologyElement) (float64, lexicalFields) {
+	var fields lexicalFields
+	fields[0] = FieldContribution{Field: "name", Match: MatchKindNone, Weight: nameWeight}
+	for _, label := range elementLabels(element) {
+		similarity, kind, distance := fuzzyMatch(query, label)
+		if fields[0].Value == "" || similarity > fields[0].Similarity {
+			fields[0].Value, fields[0].Match, fields[0].Distance, fields[0].Similarity = label, kind, distance, similarity
+		}
+	}
+
+	similarity, kind, distance := fuzzyMatch(query, element.Type)
+	fields[1] = FieldContribution{Field: "type", Value: element.Type, Match: kind, Distance: distance, Similarity: similarity, Weight: typeWeight}
 
-	descRelevance := fuzzyMatch(query, element.Description)
-	relevance += descRelevance * 0.1
+	similarity, kind, distance = fuzzyMatch(query, element.Description)
+	fields[2] = FieldContribution{Field: "description", Value: element.Description, Match: kind, Distance: distance, Similarity: similarity, Weight: descriptionWeight}
 
-	return math.Min(relevance, 1.0)
+	relevance := 0.0
+	for i := range fields {
+		fields[i].Score = fields[i].Similarity * fields[i].Weight
+		relevance += fields[i].Score
+	}
+	return math.Min(relevance, 1.0), fields
 }
 
-// fuzzyMatch calcule la similarité entre deux chaînes
-func fuzzyMatch(s1, s2 string) float64 {
+// fuzzyMatch calcule la similarité entre deux chaînes et retourne aussi le type de correspondance et la distance d'édition
+func fuzzyMatch(s1, s2 string) (float64, string, int) {
 	s1 = strings.ToLower(s1)
 	s2 = strings.ToLower(s2)
 
 	if strings.Contains(s2, s1) {
-		return 1.0
+		return 1.0, MatchKindSubstring, 0
 	}
 
 	distance := levenshtein.ComputeDistance(s1, s2)
 	maxLen := float64(max(len(s1), len(s2)))
 
 	if maxLen == 0 {
-		return 0
+		return 0, MatchKindNone, 0
 	}
 
 	similarity := 1 - float64(distance)/maxLen
 	if similarity < 0.3 {
-		return 0
+		return 0, MatchKindNone, 0
 	}
-	return math.Round(similarity*100) / 100 // Arrondir à deux décimales
+	return math.Round(similarity*100) / 100, MatchKindFuzzy, distance // Arrondir à deux décimales
 }
 
-// extractContext extrait le contexte d'un élément
-func extractContext(element *models.OntologyElement, contextSize int) string {
-	if len(element.Contexts) > 0 {
-		context := element.Contexts[0] // Prendre le premier contexte
+// extractContext extrait le contexte d'un élément à partir de ses contextes déjà fenêtrés
+func extractContext(contexts []models.JSONContext) string {
+	if len(contexts) > 0 {
+		context := contexts[0] // Prendre le premier contexte
 		before := strings.Join(context.Before, " ")
 		after := strings.Join(context.After, " ")
 		return fmt.Sprintf("%s [%s] %s", before, context.Element, after)
@@ -0,0 +1,708 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chrlesur/ontology-server/internal/logger"
+	"github.com/chrlesur/ontology-server/internal/models"
+	"github.com/chrlesur/ontology-server/internal/storage"
+)
+
+func setupTestEngine(t *testing.T) *SearchEngine {
+	ms := storage.NewMemoryStorage()
+	l, err := logger.NewLogger(logger.ERROR, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	ontology := &models.Ontology{
+		ID:   "onto1",
+		Name: "Droit public",
+		Elements: []*models.OntologyElement{
+			{
+				Name:        "Agent_Service_Public",
+				Type:        "Rôle",
+				Description: "Agent employé par une administration",
+				Contexts:    []models.JSONContext{{FileID: "file1", Element: "agent du service public"}},
+			},
+			{
+				Name:        "Service_Public",
+				Type:        "Concept/Organisation",
+				Description: "Activité d'intérêt général",
+				Contexts:    []models.JSONContext{{FileID: "file2", Element: "service public"}},
+			},
+			{
+				Name:        "Conseil_Etat",
+				Type:        "Organisation",
+				Description: "Juridiction administrative suprême",
+			},
+		},
+		Relations: []*models.Relation{
+			{Source: "Agent_Service_Public", Type: "travaille_pour", Target: "Service_Public"},
+		},
+		Source: &models.SourceMetadata{
+			Files: map[string]models.FileInfo{
+				"file1": {ID: "file1", SourceFile: "statut.txt"},
+				"file2": {ID: "file2", SourceFile: "missions.txt"},
+			},
+		},
+	}
+	if err := ms.AddOntology(ontology); err != nil {
+		t.Fatalf("Failed to add ontology: %v", err)
+	}
+
+	other := &models.Ontology{
+		ID:   "onto2",
+		Name: "Sport",
+		Elements: []*models.OntologyElement{
+			{Name: "Service_Sportif", Type: "Concept", Description: "Service rendu aux clubs"},
+		},
+	}
+	if err := ms.AddOntology(other); err != nil {
+		t.Fatalf("Failed to add ontology: %v", err)
+	}
+
+	return NewSearchEngine(ms, l)
+}
+
+// searchWith exécute une recherche sans délai ni annulation
+func searchWith(se *SearchEngine, opts SearchOptions) ([]SearchResult, *Facets, error) {
+	response, err := se.SearchWithOptions(context.Background(), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response.Results, response.Facets, nil
+}
+
+func findResult(results []SearchResult, name string) *SearchResult {
+	for i := range results {
+		if results[i].ElementName == name {
+			return &results[i]
+		}
+	}
+	return nil
+}
+
+func facetCount(facets []FacetCount, value string) int {
+	for _, f := range facets {
+		if f.Value == value {
+			return f.Count
+		}
+	}
+	return 0
+}
+
+func TestSearchWithFacets(t *testing.T) {
+	se := setupTestEngine(t)
+
+	results, facets, err := searchWith(se, SearchOptions{
+		Query:        "service",
+		ElementTypes: []string{"Organisation"},
+		WithFacets:   true,
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(results) != 1 || results[0].ElementName != "Service_Public" {
+		t.Fatalf("Expected only Service_Public for type Organisation, got %+v", results)
+	}
+
+	// La facette de type ignore son propre filtre
+	if got := facetCount(facets.ElementTypes, "Concept"); got != 2 {
+		t.Errorf("Expected 2 Concept elements in facets, got %d", got)
+	}
+	if got := facetCount(facets.ElementTypes, "Rôle"); got != 1 {
+		t.Errorf("Expected 1 Rôle element in facets, got %d", got)
+	}
+	if got := facetCount(facets.Ontologies, "onto1"); got != 1 {
+		t.Errorf("Expected 1 Organisation result in onto1, got %d", got)
+	}
+	if got := facetCount(facets.Files, "file2"); got != 1 {
+		t.Errorf("Expected 1 result in file2, got %d", got)
+	}
+	if got := facetCount(facets.RelationTypes, "travaille_pour"); got != 1 {
+		t.Errorf("Expected 1 result with relation travaille_pour, got %d", got)
+	}
+}
+
+func TestSearchWithParentType(t *testing.T) {
+	se := setupTestEngine(t)
+	if err := se.Storage.SetTypeParents("onto1", map[string]string{"Organisation": "Entité"}); err != nil {
+		t.Fatalf("SetTypeParents failed: %v", err)
+	}
+
+	// Le filtre sur un type parent inclut ses sous-types
+	results, _, err := searchWith(se, SearchOptions{Query: "service", ElementTypes: []string{"Entité"}})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ElementName != "Service_Public" {
+		t.Errorf("Expected Service_Public through its parent type, got %+v", results)
+	}
+
+	// Les suggestions appliquent le même filtre
+	suggestions := se.Suggest("serv", nil, []string{"Entité"}, 10)
+	if len(suggestions) != 1 || suggestions[0].Name != "Service_Public" {
+		t.Errorf("Expected the Service_Public suggestion through its parent type, got %+v", suggestions)
+	}
+}
+
+func TestSearchWithMultipleFilterValues(t *testing.T) {
+	se := setupTestEngine(t)
+
+	results, _, err := searchWith(se, SearchOptions{
+		Query:   "service",
+		FileIDs: []string{"file1", "file2"},
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results across file1 and file2, got %d", len(results))
+	}
+
+	results, _, err = searchWith(se, SearchOptions{
+		Query:         "service",
+		RelationTypes: []string{"travaille_pour"},
+		OntologyIDs:   []string{"onto1", "onto2"},
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results linked by travaille_pour, got %d", len(results))
+	}
+}
+
+func TestSearchInContexts(t *testing.T) {
+	se := setupTestEngine(t)
+
+	element, err := se.Storage.GetElement("Agent_Service_Public")
+	if err != nil {
+		t.Fatalf("Failed to get element: %v", err)
+	}
+	element.Contexts = []models.JSONContext{{
+		FileID:  "file1",
+		Before:  []string{"selon", "le", "Conseil", "d’État,", "l'"},
+		Element: "agent",
+		After:   []string{"public", "est", "soumis"},
+	}}
+
+	results, _, err := searchWith(se, SearchOptions{Query: "conseil d'etat"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if findResult(results, "Agent_Service_Public") != nil {
+		t.Fatal("Expected Agent_Service_Public not to match without context search")
+	}
+
+	results, _, err = searchWith(se, SearchOptions{Query: "conseil d'etat", SearchContexts: true})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	result := findResult(results, "Agent_Service_Public")
+	if result == nil {
+		t.Fatalf("Expected Agent_Service_Public to match through its context, got %+v", results)
+	}
+
+	matches := result.ContextMatches
+	if len(matches) != 1 || matches[0].Score != 1.0 {
+		t.Fatalf("Expected one exact context match, got %+v", matches)
+	}
+
+	snippet := []rune(matches[0].Snippet)
+	var kinds []string
+	for _, span := range matches[0].Highlights {
+		kinds = append(kinds, span.Kind+":"+string(snippet[span.Start:span.End]))
+	}
+	expected := []string{"query:Conseil", "query:d’État", "element:agent"}
+	if len(kinds) != len(expected) {
+		t.Fatalf("Expected highlights %v, got %v", expected, kinds)
+	}
+	for i := range expected {
+		if kinds[i] != expected[i] {
+			t.Errorf("Expected highlight %s, got %s", expected[i], kinds[i])
+		}
+	}
+}
+
+func TestContextWindows(t *testing.T) {
+	se := setupTestEngine(t)
+
+	dir := t.TempDir()
+	text := "Dans le cas des joueuses internationales le Conseil d'État nous dit que ce sont les agents du service public"
+	if err := os.WriteFile(filepath.Join(dir, "source.txt"), []byte(text), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+	source := &models.SourceMetadata{
+		Files: map[string]models.FileInfo{
+			"file1": {ID: "file1", SourceFile: "source.txt", Directory: dir},
+		},
+	}
+
+	stored := models.JSONContext{
+		FileID:       "file1",
+		FilePosition: 7,
+		Length:       1,
+		Before:       []string{"internationales", "le"},
+		Element:      "Conseil",
+		After:        []string{"d'État", "nous", "dit"},
+	}
+
+	trimmed := se.sourceReader().contextWindow(stored, nil, 1)
+	if strings.Join(trimmed.Before, " ") != "le" || strings.Join(trimmed.After, " ") != "d'État" {
+		t.Errorf("Expected trimmed window [le] Conseil [d'État], got %v %v", trimmed.Before, trimmed.After)
+	}
+
+	widened := se.sourceReader().contextWindow(stored, source, 4)
+	if got := strings.Join(widened.Before, " "); got != "des joueuses internationales le" {
+		t.Errorf("Expected widened before window, got %q", got)
+	}
+	if got := strings.Join(widened.After, " "); got != "d'État nous dit que" {
+		t.Errorf("Expected widened after window, got %q", got)
+	}
+
+	// Un même lecteur ne relit pas le fichier source pour les contextes suivants
+	reader := se.sourceReader()
+	reader.contextWindow(stored, source, 4)
+	if err := os.Remove(filepath.Join(dir, "source.txt")); err != nil {
+		t.Fatalf("Failed to remove source file: %v", err)
+	}
+	if again := reader.contextWindow(stored, source, 4); len(again.Before) != 4 {
+		t.Errorf("Expected the source file to be read once per request, got %v", again.Before)
+	}
+
+	// Un fichier source qui ne correspond plus au contexte stocké est ignoré
+	moved := stored
+	moved.Element = "Tribunal"
+	if fallback := reader.contextWindow(moved, source, 4); len(fallback.Before) != 2 {
+		t.Errorf("Expected stored window when the element does not match the source, got %v", fallback.Before)
+	}
+	stored.Before = []string{"autre", "mot"}
+	fallback := reader.contextWindow(stored, source, 4)
+	if strings.Join(fallback.Before, " ") != "autre mot" {
+		t.Errorf("Expected stored window when source does not match, got %v", fallback.Before)
+	}
+}
+
+func TestSourceCacheBound(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("mot ", 100)), 0644); err != nil {
+			t.Fatalf("Failed to write source file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	// La limite permet de conserver deux fichiers : le moins récemment lu est oublié
+	sc := newSourceCache(2 * (len(paths[0]) + 400 + 100*wordOverhead))
+	for _, path := range []string{paths[0], paths[1], paths[0], paths[2]} {
+		if words, err := sc.words(path); err != nil || len(words) != 100 {
+			t.Fatalf("Expected 100 words from %s, got %d (%v)", path, len(words), err)
+		}
+	}
+	if _, exists := sc.entries[paths[1]]; exists || len(sc.entries) != 2 || sc.bytes > sc.maxBytes {
+		t.Errorf("Expected %s to be evicted within %d bytes, got %d entries and %d bytes", paths[1], sc.maxBytes, len(sc.entries), sc.bytes)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	se := setupTestEngine(t)
+
+	suggestions := se.Suggest("serv", nil, nil, 10)
+	if len(suggestions) != 3 {
+		t.Fatalf("Expected 3 suggestions for 'serv', got %+v", suggestions)
+	}
+	// Les correspondances sur le nom complet passent avant celles sur un mot intérieur
+	if suggestions[0].Name == "Agent_Service_Public" {
+		t.Errorf("Expected full-name completions first, got %+v", suggestions)
+	}
+
+	suggestions = se.Suggest("service p", []string{"onto1"}, []string{"Rôle"}, 10)
+	if len(suggestions) != 1 || suggestions[0].Name != "Agent_Service_Public" {
+		t.Fatalf("Expected Agent_Service_Public for scoped prefix, got %+v", suggestions)
+	}
+	if suggestions[0].OntologyName != "Droit public" || suggestions[0].Type != "Rôle" {
+		t.Errorf("Expected type and ontology in suggestion, got %+v", suggestions[0])
+	}
+
+	// L'index suit les ontologies ajoutées après sa construction
+	se.Storage.AddOntology(&models.Ontology{
+		ID:       "onto3",
+		Elements: []*models.OntologyElement{{Name: "Sérvitude", Type: "Concept"}},
+	})
+	if suggestions := se.Suggest("servi", nil, nil, 10); len(suggestions) != 4 {
+		t.Errorf("Expected new ontology to be indexed, got %+v", suggestions)
+	}
+
+	// Une clé courte reste proposée même si des milliers de clés plus longues la précèdent dans l'ordre alphabétique
+	var elements []*models.OntologyElement
+	for i := 0; i < 2*maxSuggestCandidates; i++ {
+		elements = append(elements, &models.OntologyElement{Name: fmt.Sprintf("Tribunal_%04d", i), Type: "Organisation"})
+	}
+	elements = append(elements, &models.OntologyElement{Name: "Trivia", Type: "Concept"})
+	se.Storage.AddOntology(&models.Ontology{ID: "onto4", Elements: elements})
+	if suggestions := se.Suggest("tri", nil, nil, 1); len(suggestions) != 1 || suggestions[0].Name != "Trivia" {
+		t.Errorf("Expected the shortest completion first, got %+v", suggestions)
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	se := setupTestEngine(t)
+
+	alternatives := se.DidYouMean("sevrice publik", nil, 5)
+	if len(alternatives) == 0 || alternatives[0] != "service public" {
+		t.Fatalf("Expected 'service public' as first alternative, got %v", alternatives)
+	}
+
+	if alternatives := se.DidYouMean("service public", nil, 5); len(alternatives) != 0 {
+		t.Errorf("Expected no alternative for known terms, got %v", alternatives)
+	}
+
+	// Restreindre le vocabulaire à une ontologie
+	alternatives = se.DidYouMean("sportiff", []string{"onto1"}, 5)
+	for _, alternative := range alternatives {
+		if alternative == "sportif" {
+			t.Errorf("Expected vocabulary of onto2 to be excluded, got %v", alternatives)
+		}
+	}
+}
+
+func TestPhoneticKey(t *testing.T) {
+	pairs := [][2]string{
+		{"fonctionnaire", "fonctionaire"},
+		{"théâtre", "teatre"},
+		{"photographie", "fotografi"},
+	}
+	for _, pair := range pairs {
+		if phoneticKey(pair[0]) != phoneticKey(pair[1]) {
+			t.Errorf("Expected %s and %s to share a phonetic key, got %s and %s",
+				pair[0], pair[1], phoneticKey(pair[0]), phoneticKey(pair[1]))
+		}
+	}
+}
+
+func TestSearchWithSynonymsAndAltLabels(t *testing.T) {
+	se := setupTestEngine(t)
+
+	results, _, err := searchWith(se, SearchOptions{Query: "fonctionnaire"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if findResult(results, "Agent_Service_Public") != nil {
+		t.Fatal("Expected no match before synonyms are loaded")
+	}
+
+	// Les synonymes d'une autre ontologie ne s'appliquent pas
+	se.Synonyms.Load("onto2", [][]string{{"fonctionnaire", "agent service public"}})
+	results, _, _ = searchWith(se, SearchOptions{Query: "fonctionnaire"})
+	if findResult(results, "Agent_Service_Public") != nil {
+		t.Fatal("Expected synonyms of onto2 not to apply to onto1")
+	}
+
+	se.Synonyms.Load("", [][]string{{"fonctionnaire", "agent service public"}})
+	results, _, _ = searchWith(se, SearchOptions{Query: "fonctionnaire"})
+	if findResult(results, "Agent_Service_Public") == nil {
+		t.Fatalf("Expected global synonym to expand the query, got %+v", results)
+	}
+
+	if _, err := se.Storage.SetElementAltLabels("onto1", "Conseil_Etat", []string{"Haute juridiction"}); err != nil {
+		t.Fatalf("Failed to set alternate labels: %v", err)
+	}
+	results, _, _ = searchWith(se, SearchOptions{Query: "haute juridiction"})
+	if findResult(results, "Conseil_Etat") == nil {
+		t.Errorf("Expected alternate label to be searchable, got %+v", results)
+	}
+	if suggestions := se.Suggest("haute", nil, nil, 5); len(suggestions) != 1 || suggestions[0].Name != "Conseil_Etat" {
+		t.Errorf("Expected alternate label to be suggested, got %+v", suggestions)
+	}
+}
+
+func TestSearchExplain(t *testing.T) {
+	se := setupTestEngine(t)
+	se.Synonyms.Load("", [][]string{{"fonctionnaire", "agent service public"}})
+
+	results, _, err := searchWith(se, SearchOptions{
+		Query:        "fonctionnaire",
+		ElementTypes: []string{"Rôle"},
+		Explain:      true,
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	result := findResult(results, "Agent_Service_Public")
+	if result == nil || result.Explanation == nil {
+		t.Fatalf("Expected an explained result for Agent_Service_Public, got %+v", results)
+	}
+
+	explanation := result.Explanation
+	if explanation.Source != MatchSourceSynonym || explanation.MatchedQuery != "agent service public" {
+		t.Errorf("Expected match through synonym 'agent service public', got %s via %q", explanation.Source, explanation.MatchedQuery)
+	}
+	if explanation.Relevance != result.Relevance {
+		t.Errorf("Expected explanation relevance %.2f to equal result relevance %.2f", explanation.Relevance, result.Relevance)
+	}
+	if len(explanation.Fields) != 3 || explanation.Fields[0].Field != "name" || explanation.Fields[0].Match != MatchKindFuzzy {
+		t.Errorf("Expected fuzzy name contribution first, got %+v", explanation.Fields)
+	}
+	total := 0.0
+	for _, field := range explanation.Fields {
+		total += field.Score
+	}
+	if total*0.95-result.Relevance > 0.01 || result.Relevance-total*0.95 > 0.01 {
+		t.Errorf("Expected field scores to add up to the relevance, got %.3f for %.3f", total*0.95, result.Relevance)
+	}
+	if types := explanation.Filters["type"]; len(types) != 1 || types[0] != "Rôle" {
+		t.Errorf("Expected applied type filter in explanation, got %v", explanation.Filters)
+	}
+
+	results, _, _ = searchWith(se, SearchOptions{Query: "fonctionnaire"})
+	if result := findResult(results, "Agent_Service_Public"); result != nil && result.Explanation != nil {
+		t.Error("Expected no explanation unless requested")
+	}
+}
+
+func TestSearchCancelled(t *testing.T) {
+	se := setupTestEngine(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	response, err := se.SearchWithOptions(ctx, SearchOptions{Query: "service"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if !response.Partial {
+		t.Error("Expected a cancelled search to be flagged as partial")
+	}
+	if len(response.Results) != 0 {
+		t.Errorf("Expected no result from a cancelled search, got %d", len(response.Results))
+	}
+
+	response, err = se.SearchWithOptions(context.Background(), SearchOptions{Query: "service"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if response.Partial || len(response.Results) == 0 {
+		t.Errorf("Expected a complete search with results, got partial=%v and %d results", response.Partial, len(response.Results))
+	}
+}
+
+func TestSearchCache(t *testing.T) {
+	se := setupTestEngine(t)
+
+	// Les variations de casse et d'espaces partagent la même entrée
+	searchWith(se, SearchOptions{Query: "service"})
+	searchWith(se, SearchOptions{Query: "  Service "})
+	searchWith(se, SearchOptions{Query: "service", OntologyIDs: []string{"onto1"}})
+	stats := se.Cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Fatalf("Unexpected cache stats: %+v", stats)
+	}
+
+	// Modifier onto2 n'invalide que les recherches qui l'ont parcourue
+	onto2, _ := se.Storage.GetOntology("onto2")
+	updated := *onto2
+	updated.Elements = append(append([]*models.OntologyElement(nil), onto2.Elements...),
+		&models.OntologyElement{Name: "Service_Civique", Type: "Concept"})
+	if err := se.Storage.UpdateOntology(&updated); err != nil {
+		t.Fatalf("Failed to update ontology: %v", err)
+	}
+	stats = se.Cache.Stats()
+	if stats.Entries != 1 || stats.Invalidations != 1 {
+		t.Fatalf("Expected only the unfiltered search to be invalidated, got %+v", stats)
+	}
+
+	results, _, _ := searchWith(se, SearchOptions{Query: "service"})
+	if findResult(results, "Service_Civique") == nil {
+		t.Error("Expected fresh results after invalidation")
+	}
+
+	// La limite en nombre d'entrées évince les moins récemment utilisées
+	se.Cache.Resize(1, 0)
+	if stats = se.Cache.Stats(); stats.Entries != 1 || stats.Evictions != 1 {
+		t.Errorf("Expected one eviction after resize, got %+v", stats)
+	}
+}
+
+func TestSearchCacheInvalidationRace(t *testing.T) {
+	se := setupTestEngine(t)
+	opts := SearchOptions{Query: "stale", OntologyIDs: []string{"onto1"}}
+
+	// Une invalidation survenue entre Get et Put empêche de conserver une réponse périmée
+	_, generation, _ := se.Cache.Get("stale")
+	se.Cache.Invalidate("onto1")
+	se.Cache.Put("stale", opts, &SearchResponse{}, generation)
+	if _, _, ok := se.Cache.Get("stale"); ok {
+		t.Error("Expected the response computed before the invalidation not to be cached")
+	}
+
+	// Les scores sémantiques dépendent de tout le corpus : l'entrée dépend de toutes les ontologies
+	searchWith(se, SearchOptions{Query: "service", OntologyIDs: []string{"onto1"}, Mode: SearchModeSemantic})
+	se.Cache.Invalidate("onto2")
+	if stats := se.Cache.Stats(); stats.Entries != 0 {
+		t.Errorf("Expected the semantic entry to be invalidated by another ontology, got %+v", stats)
+	}
+}
+
+func TestSemanticIndexGeneration(t *testing.T) {
+	si := NewSemanticIndex()
+	previous := &models.Ontology{ID: "onto1", Elements: []*models.OntologyElement{{Name: "Ancien_Nom"}}}
+	current := &models.Ontology{ID: "onto1", Elements: []*models.OntologyElement{{Name: "Nouveau_Nom"}}}
+
+	// Une modification et une reconstruction surviennent pendant une construction plus lente
+	si.refresh(func() []*models.Ontology {
+		si.invalidate("onto1")
+		si.refresh(func() []*models.Ontology { return []*models.Ontology{current} })
+		return []*models.Ontology{previous}
+	})
+	if si.vector(current.Elements[0]) == nil || si.vector(previous.Elements[0]) != nil {
+		t.Errorf("Expected the newer build to be kept over the older one")
+	}
+}
+
+func TestSemanticSearch(t *testing.T) {
+	se := setupTestEngine(t)
+	query := "juridictions administratives"
+
+	results, _, _ := searchWith(se, SearchOptions{Query: query})
+	if findResult(results, "Conseil_Etat") != nil {
+		t.Fatal("Expected the paraphrase not to match lexically")
+	}
+
+	results, _, err := searchWith(se, SearchOptions{Query: query, Mode: SearchModeSemantic, Explain: true})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) == 0 || results[0].ElementName != "Conseil_Etat" {
+		t.Fatalf("Expected Conseil_Etat first in semantic mode, got %+v", results)
+	}
+	if results[0].Explanation.Source != MatchSourceSemantic || results[0].Explanation.Threshold != semanticThreshold {
+		t.Errorf("Unexpected explanation: %+v", results[0].Explanation)
+	}
+	// Le score lexical n'intervient pas en mode sémantique
+	if fields := results[0].Explanation.Fields; len(fields) != 1 || fields[0].Field != "semantic" {
+		t.Errorf("Expected only the semantic contribution, got %+v", fields)
+	}
+
+	results, _, _ = searchWith(se, SearchOptions{Query: query, Mode: SearchModeHybrid})
+	if findResult(results, "Conseil_Etat") == nil {
+		t.Error("Expected Conseil_Etat in hybrid mode")
+	}
+	results, _, _ = searchWith(se, SearchOptions{Query: "service public", Mode: SearchModeHybrid})
+	if len(results) == 0 || results[0].ElementName != "Service_Public" {
+		t.Errorf("Expected lexical matches to stay first in hybrid mode, got %+v", results)
+	}
+}
+
+func TestSimilarElements(t *testing.T) {
+	se := setupTestEngine(t)
+
+	similar, err := se.SimilarElements("Service_Public", 10)
+	if err != nil {
+		t.Fatalf("SimilarElements failed: %v", err)
+	}
+	if len(similar) == 0 || similar[0].ElementName != "Agent_Service_Public" {
+		t.Fatalf("Expected Agent_Service_Public first, got %+v", similar)
+	}
+	if similar[0].CoOccurrence == 0 {
+		t.Errorf("Expected a co-occurrence signal, got %+v", similar[0])
+	}
+	for _, s := range similar {
+		if s.ElementName == "Service_Public" {
+			t.Error("The reference element must not be returned")
+		}
+	}
+	found := false
+	for _, s := range similar {
+		found = found || (s.ElementName == "Service_Sportif" && s.OntologyID == "onto2")
+	}
+	if !found {
+		t.Errorf("Expected similar elements from other ontologies, got %+v", similar)
+	}
+
+	if _, err := se.SimilarElements("Inconnu", 10); err == nil {
+		t.Error("Expected an error for an unknown element")
+	}
+
+	// Les profils sont reconstruits après une modification des ontologies
+	se.Storage.AddOntology(&models.Ontology{
+		ID:       "onto3",
+		Elements: []*models.OntologyElement{{Name: "Usager", Type: "Rôle"}},
+		Relations: []*models.Relation{
+			{Source: "Service_Public", Type: "sert", Target: "Usager"},
+			{Source: "Usager", Type: "sollicite", Target: "Service_Public"},
+		},
+	})
+	similar, _ = se.SimilarElements("Usager", 10)
+	if len(similar) == 0 || strings.Join(similar[0].SharedNeighbours, ",") != "Service Public" {
+		t.Errorf("Expected an element sharing Service Public as neighbour, got %+v", similar)
+	}
+	if profiles := se.similarityProfiles(); len(profiles.occurrences["Usager"]) != 1 {
+		t.Errorf("Expected Usager to be profiled once, got %v", profiles.occurrences["Usager"])
+	}
+}
+
+func TestPatternSearch(t *testing.T) {
+	se := setupTestEngine(t)
+
+	results, _, err := searchWith(se, SearchOptions{Query: "^Agent_.*_Public$", Mode: SearchModeRegex})
+	if err != nil {
+		t.Fatalf("Regex search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ElementName != "Agent_Service_Public" {
+		t.Errorf("Expected only Agent_Service_Public, got %+v", results)
+	}
+
+	// Les motifs à jokers sont ancrés et insensibles à la casse
+	results, _, _ = searchWith(se, SearchOptions{Query: "*_public", Mode: SearchModeWildcard})
+	if len(results) != 2 || findResult(results, "Service_Public") == nil || findResult(results, "Agent_Service_Public") == nil {
+		t.Errorf("Expected the two *_Public elements, got %+v", results)
+	}
+
+	// Une correspondance sur la seule description reste moins pertinente
+	results, _, _ = searchWith(se, SearchOptions{Query: "*administration*", Mode: SearchModeWildcard, Explain: true})
+	if len(results) != 1 || results[0].Relevance != patternDescriptionRelevance || results[0].Explanation.Source != MatchSourcePattern {
+		t.Errorf("Expected a description-only match, got %+v", results)
+	}
+
+	invalid := []string{"Agent_(", "a{1000}", strings.Repeat("(a|b)", 50)}
+	for _, pattern := range invalid {
+		if _, _, err := searchWith(se, SearchOptions{Query: pattern, Mode: SearchModeRegex}); !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("Expected pattern %q to be rejected, got %v", pattern, err)
+		}
+	}
+}
+
+func TestSearchStream(t *testing.T) {
+	se := setupTestEngine(t)
+
+	var streamed []SearchResult
+	response, err := se.SearchStream(context.Background(), SearchOptions{Query: "service"}, false, func(result SearchResult) error {
+		streamed = append(streamed, result)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SearchStream failed: %v", err)
+	}
+	expected, _, _ := searchWith(se, SearchOptions{Query: "service"})
+	if response.Partial || len(streamed) != len(expected) {
+		t.Errorf("Expected %d streamed results, got %d (partial=%v)", len(expected), len(streamed), response.Partial)
+	}
+
+	// Une erreur d'écriture interrompt la recherche
+	stop := errors.New("client gone")
+	if _, err := se.SearchStream(context.Background(), SearchOptions{Query: "service"}, false, func(SearchResult) error {
+		return stop
+	}); !errors.Is(err, stop) {
+		t.Errorf("Expected the emit error to be returned, got %v", err)
+	}
+}
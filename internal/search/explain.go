@@ -0,0 +1,134 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+)
+
+// Origines possibles du score retenu pour un résultat
+const (
+	MatchSourceLexical  = "lexical"
+	MatchSourceSynonym  = "synonym"
+	MatchSourceContext  = "context"
+	MatchSourceSemantic = "semantic"
+	MatchSourcePattern  = "pattern"
+)
+
+// Types de correspondance d'un champ avec la requête
+const (
+	MatchKindSubstring = "substring"
+	MatchKindFuzzy     = "fuzzy"
+	MatchKindPattern   = "pattern"
+	MatchKindNone      = "none"
+)
+
+// relevanceFormula décrit la combinaison des scores appliquée par le moteur
+var relevanceFormula = fmt.Sprintf("lexical = min(1, %g*name + %g*type + %g*description), x%g when matched through a synonym; "+
+	"mode=semantic: TF-IDF cosine similarity; mode=hybrid: %g*lexical + %g*semantic; max with context score when in_contexts=true",
+	nameWeight, typeWeight, descriptionWeight, synonymPenalty, hybridLexicalWeight, 1-hybridLexicalWeight)
+
+// FieldContribution détaille l'apport d'un champ au score de pertinence
+type FieldContribution struct {
+	Field      string  `json:"field"`
+	Value      string  `json:"value"`
+	Match      string  `json:"match"`
+	Distance   int     `json:"distance,omitempty"`
+	Similarity float64 `json:"similarity"`
+	Weight     float64 `json:"weight"`
+	Score      float64 `json:"score"`
+}
+
+// Explanation indique pourquoi un élément a été retenu et comment son score a été obtenu
+type Explanation struct {
+	Relevance    float64             `json:"relevance"`
+	Threshold    float64             `json:"threshold"`
+	Formula      string              `json:"formula"`
+	Source       string              `json:"source"`
+	MatchedQuery string              `json:"matched_query"`
+	Fields       []FieldContribution `json:"fields"`
+	MatchedTerms []string            `json:"matched_terms"`
+	Filters      map[string][]string `json:"filters"`
+}
+
+// matchedTerms retourne les termes de la requête présents dans les libellés, le type, la description ou les contextes retenus
+func matchedTerms(query string, element *models.OntologyElement, contextMatches []ContextMatch) []string {
+	texts := append(elementLabels(element), element.Type, element.Description)
+	for _, match := range contextMatches {
+		texts = append(texts, match.Snippet)
+	}
+	for i, text := range texts {
+		texts[i] = normalizeSuggestKey(text)
+	}
+
+	terms := []string{}
+	for _, term := range queryTerms(query) {
+		for _, text := range texts {
+			if strings.Contains(text, term) {
+				terms = append(terms, term)
+				break
+			}
+		}
+	}
+	return terms
+}
+
+// appliedFilters liste les filtres non vides d'une recherche
+func appliedFilters(opts SearchOptions) map[string][]string {
+	filters := make(map[string][]string)
+	add := func(name string, values []string) {
+		if len(values) > 0 {
+			filters[name] = values
+		}
+	}
+	add("ontology_id", opts.OntologyIDs)
+	add("type", opts.ElementTypes)
+	add("file_id", opts.FileIDs)
+	add("relation_type", opts.RelationTypes)
+	return filters
+}
+
+// explainResult construit l'explication d'un résultat retenu,
+// à partir des contributions des champs calculées par calculateRelevance.
+// En mode sémantique, le score lexical n'intervient pas et ses champs ne sont pas listés.
+func explainResult(opts SearchOptions, element *models.OntologyElement, lexical lexicalFields, relevance, semantic float64, source, matchedQuery string, contextMatches []ContextMatch) *Explanation {
+	var fields []FieldContribution
+	if opts.Mode != SearchModeSemantic {
+		fields = append(fields, lexical[:]...)
+	}
+	if opts.Mode == SearchModeSemantic || opts.Mode == SearchModeHybrid {
+		weight := 1.0
+		if opts.Mode == SearchModeHybrid {
+			weight = 1 - hybridLexicalWeight
+		}
+		fields = append(fields, FieldContribution{
+			Field:      "semantic",
+			Match:      MatchSourceSemantic,
+			Similarity: semantic,
+			Weight:     weight,
+			Score:      semantic * weight,
+		})
+	}
+	if opts.SearchContexts {
+		context := FieldContribution{Field: "context", Match: MatchKindNone, Weight: 1.0}
+		if len(contextMatches) > 0 {
+			context.Value = contextMatches[0].Snippet
+			context.Similarity = contextMatches[0].Score
+			context.Score = contextMatches[0].Score
+			context.Match = MatchKindSubstring
+		}
+		fields = append(fields, context)
+	}
+
+	return &Explanation{
+		Relevance:    relevance,
+		Threshold:    modeThreshold(opts.Mode),
+		Formula:      relevanceFormula,
+		Source:       source,
+		MatchedQuery: matchedQuery,
+		Fields:       fields,
+		MatchedTerms: matchedTerms(opts.Query, element, contextMatches),
+		Filters:      appliedFilters(opts),
+	}
+}
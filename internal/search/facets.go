@@ -0,0 +1,211 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+	"github.com/chrlesur/ontology-server/internal/storage"
+)
+
+// FacetCount représente le nombre de résultats pour une valeur de facette
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// Facets regroupe les décomptes de résultats par dimension de filtrage
+type Facets struct {
+	ElementTypes  []FacetCount `json:"element_types"`
+	Ontologies    []FacetCount `json:"ontologies"`
+	Files         []FacetCount `json:"files"`
+	RelationTypes []FacetCount `json:"relation_types"`
+}
+
+// facetMatch indique, pour un élément, quels filtres il satisfait
+type facetMatch struct {
+	ontology    bool
+	elementType bool
+	file        bool
+	relation    bool
+}
+
+func (m facetMatch) all() bool {
+	return m.ontology && m.elementType && m.file && m.relation
+}
+
+// facetCounter accumule les décomptes de facettes depuis plusieurs goroutines.
+// Chaque dimension est comptée en ignorant son propre filtre, ce qui permet
+// de sélectionner plusieurs valeurs d'une même facette côté client.
+type facetCounter struct {
+	mutex         sync.Mutex
+	elementTypes  map[string]*FacetCount
+	ontologies    map[string]*FacetCount
+	files         map[string]*FacetCount
+	relationTypes map[string]*FacetCount
+}
+
+func newFacetCounter() *facetCounter {
+	return &facetCounter{
+		elementTypes:  make(map[string]*FacetCount),
+		ontologies:    make(map[string]*FacetCount),
+		files:         make(map[string]*FacetCount),
+		relationTypes: make(map[string]*FacetCount),
+	}
+}
+
+// add comptabilise un élément correspondant à la requête dans chaque facette pertinente
+func (fc *facetCounter) add(onto *models.Ontology, element *models.OntologyElement, relationTypes []string, m facetMatch) {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if m.ontology && m.file && m.relation {
+		for _, t := range splitElementType(element.Type) {
+			increment(fc.elementTypes, t, "")
+		}
+	}
+
+	if m.elementType && m.file && m.relation {
+		increment(fc.ontologies, onto.ID, onto.Name)
+	}
+
+	if m.ontology && m.elementType && m.relation {
+		seen := make(map[string]bool)
+		for _, ctx := range element.Contexts {
+			if ctx.FileID == "" || seen[ctx.FileID] {
+				continue
+			}
+			seen[ctx.FileID] = true
+			label := ""
+			if onto.Source != nil {
+				if fileInfo, exists := onto.Source.Files[ctx.FileID]; exists {
+					label = fileInfo.SourceFile
+				}
+			}
+			increment(fc.files, ctx.FileID, label)
+		}
+	}
+
+	if m.ontology && m.elementType && m.file {
+		for _, relType := range relationTypes {
+			increment(fc.relationTypes, relType, "")
+		}
+	}
+}
+
+// facets retourne les décomptes triés par nombre décroissant puis par valeur
+func (fc *facetCounter) facets() *Facets {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	return &Facets{
+		ElementTypes:  sortedFacetCounts(fc.elementTypes),
+		Ontologies:    sortedFacetCounts(fc.ontologies),
+		Files:         sortedFacetCounts(fc.files),
+		RelationTypes: sortedFacetCounts(fc.relationTypes),
+	}
+}
+
+func increment(counts map[string]*FacetCount, value, label string) {
+	if fc, exists := counts[value]; exists {
+		fc.Count++
+		return
+	}
+	counts[value] = &FacetCount{Value: value, Label: label, Count: 1}
+}
+
+func sortedFacetCounts(counts map[string]*FacetCount) []FacetCount {
+	sorted := make([]FacetCount, 0, len(counts))
+	for _, fc := range counts {
+		sorted = append(sorted, *fc)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Value < sorted[j].Value
+	})
+	return sorted
+}
+
+// splitElementType découpe un type combiné ("A/B") en types individuels
+func splitElementType(elementType string) []string {
+	var types []string
+	for _, t := range strings.Split(elementType, "/") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// matchesElementType vérifie si le type d'un élément, ou l'une de ses composantes, fait partie des types demandés,
+// les types étant comparés sous leur forme normalisée (storage.NormalizeType)
+func matchesElementType(elementType string, wanted []string) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	candidates := append([]string{elementType}, splitElementType(elementType)...)
+	for _, w := range wanted {
+		key := storage.NormalizeType(w)
+		for _, c := range candidates {
+			if storage.NormalizeType(c) == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesFileIDs vérifie si l'un des contextes de l'élément provient des fichiers demandés
+func matchesFileIDs(element *models.OntologyElement, fileIDs []string) bool {
+	if len(fileIDs) == 0 {
+		return true
+	}
+	for _, ctx := range element.Contexts {
+		if containsValue(fileIDs, ctx.FileID) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAnyValue vérifie si l'une des valeurs fait partie des valeurs demandées
+func matchesAnyValue(values []string, wanted []string) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if containsValue(wanted, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// elementRelationTypes associe à chaque nom d'élément normalisé les types de relations auxquelles il participe
+func elementRelationTypes(onto *models.Ontology) map[string][]string {
+	relationTypes := make(map[string][]string)
+	for _, relation := range onto.Relations {
+		source, target := storage.RelationEndpoints(relation)
+		for _, end := range []string{source, target} {
+			name := storage.NormalizeElementName(end)
+			if !containsValue(relationTypes[name], relation.Type) {
+				relationTypes[name] = append(relationTypes[name], relation.Type)
+			}
+		}
+	}
+	return relationTypes
+}
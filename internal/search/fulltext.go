@@ -0,0 +1,176 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+)
+
+// Types de passages mis en évidence dans un extrait de contexte
+const (
+	HighlightElement = "element"
+	HighlightQuery   = "query"
+)
+
+// HighlightSpan délimite un passage à mettre en évidence dans un extrait.
+// Les positions sont exprimées en caractères (runes), fin exclue.
+type HighlightSpan struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Kind  string `json:"kind"`
+}
+
+// ContextMatch représente un contexte d'occurrence correspondant à la requête
+type ContextMatch struct {
+	FileID       string          `json:"file_id"`
+	FilePosition int             `json:"file_position"`
+	Position     int             `json:"position"`
+	Snippet      string          `json:"snippet"`
+	Highlights   []HighlightSpan `json:"highlights"`
+	Score        float64         `json:"score"`
+}
+
+// accentFolding associe les caractères accentués du français à leur forme de base
+var accentFolding = map[rune]rune{
+	'à': 'a', 'â': 'a', 'ä': 'a', 'á': 'a',
+	'ç': 'c',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'î': 'i', 'ï': 'i', 'í': 'i',
+	'ô': 'o', 'ö': 'o', 'ó': 'o',
+	'ù': 'u', 'û': 'u', 'ü': 'u', 'ú': 'u',
+	'ÿ': 'y',
+	'’': '\'', '‘': '\'',
+}
+
+// foldRunes met un texte en minuscules et retire les accents, caractère par
+// caractère, de sorte que les positions restent alignées sur le texte d'origine
+func foldRunes(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		r = unicode.ToLower(r)
+		if folded, exists := accentFolding[r]; exists {
+			r = folded
+		}
+		runes[i] = r
+	}
+	return runes
+}
+
+// foldText retourne la forme normalisée d'un texte pour la comparaison
+func foldText(text string) string {
+	return string(foldRunes(text))
+}
+
+// queryTerms découpe une requête en termes normalisés, en ignorant les termes d'un seul caractère
+func queryTerms(query string) []string {
+	var terms []string
+	for _, term := range strings.Fields(foldText(query)) {
+		if len([]rune(term)) > 1 {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// contextSnippet reconstitue le texte d'un contexte et la zone occupée par l'élément
+func contextSnippet(ctx models.JSONContext) (string, HighlightSpan) {
+	before := strings.Join(ctx.Before, " ")
+	after := strings.Join(ctx.After, " ")
+
+	var builder strings.Builder
+	if before != "" {
+		builder.WriteString(before)
+		builder.WriteString(" ")
+	}
+	start := len([]rune(builder.String()))
+	builder.WriteString(ctx.Element)
+	end := start + len([]rune(ctx.Element))
+	if after != "" {
+		builder.WriteString(" ")
+		builder.WriteString(after)
+	}
+
+	return builder.String(), HighlightSpan{Start: start, End: end, Kind: HighlightElement}
+}
+
+// findOccurrences retourne les positions (en runes) de toutes les occurrences de term dans text
+func findOccurrences(text, term []rune) []HighlightSpan {
+	var spans []HighlightSpan
+	if len(term) == 0 {
+		return spans
+	}
+	for i := 0; i+len(term) <= len(text); i++ {
+		if string(text[i:i+len(term)]) == string(term) {
+			spans = append(spans, HighlightSpan{Start: i, End: i + len(term), Kind: HighlightQuery})
+			i += len(term) - 1
+		}
+	}
+	return spans
+}
+
+// matchContext évalue un contexte par rapport aux termes de la requête.
+// Le score vaut 1 si la requête apparaît telle quelle, sinon la proportion de termes trouvés.
+func matchContext(query string, terms []string, ctx models.JSONContext) (ContextMatch, bool) {
+	snippet, elementSpan := contextSnippet(ctx)
+	folded := foldRunes(snippet)
+
+	highlights := []HighlightSpan{elementSpan}
+	found := 0
+	for _, term := range terms {
+		spans := findOccurrences(folded, []rune(term))
+		if len(spans) > 0 {
+			found++
+			highlights = append(highlights, spans...)
+		}
+	}
+	if found == 0 {
+		return ContextMatch{}, false
+	}
+
+	score := float64(found) / float64(len(terms))
+	if strings.Contains(string(folded), strings.Join(strings.Fields(foldText(query)), " ")) {
+		score = 1.0
+	}
+
+	sort.SliceStable(highlights, func(i, j int) bool {
+		return highlights[i].Start < highlights[j].Start
+	})
+
+	return ContextMatch{
+		FileID:       ctx.FileID,
+		FilePosition: ctx.FilePosition,
+		Position:     ctx.Position,
+		Snippet:      snippet,
+		Highlights:   highlights,
+		Score:        score,
+	}, true
+}
+
+// matchContexts recherche la requête dans les contextes d'occurrence d'un élément.
+// Elle retourne les contextes pertinents triés par score ainsi que le meilleur score obtenu.
+func matchContexts(query string, contexts []models.JSONContext) ([]ContextMatch, float64) {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil, 0
+	}
+
+	var matches []ContextMatch
+	best := 0.0
+	for _, ctx := range contexts {
+		match, ok := matchContext(query, terms, ctx)
+		if !ok || match.Score <= 0.3 {
+			continue
+		}
+		matches = append(matches, match)
+		if match.Score > best {
+			best = match.Score
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches, best
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+	"github.com/chrlesur/ontology-server/internal/regexlimit"
+)
+
+// Modes de recherche par motif
+const (
+	SearchModeRegex    = "regex"
+	SearchModeWildcard = "wildcard"
+)
+
+// patternSearchTimeout borne la durée d'une recherche par motif
+const patternSearchTimeout = 2 * time.Second
+
+// Pertinence d'un élément retenu par motif selon le champ qui correspond
+const (
+	patternNameRelevance        = 1.0
+	patternDescriptionRelevance = 0.5
+)
+
+// ErrInvalidPattern signale un motif mal formé ou dépassant les limites de complexité
+var ErrInvalidPattern = errors.New("invalid search pattern")
+
+// isPatternMode indique si le mode de recherche interprète la requête comme un motif
+func isPatternMode(mode string) bool {
+	return mode == SearchModeRegex || mode == SearchModeWildcard
+}
+
+// wildcardToRegex convertit un motif à jokers (* et ?) en expression régulière ancrée, insensible à la casse
+func wildcardToRegex(pattern string) string {
+	var builder strings.Builder
+	builder.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	builder.WriteString("$")
+	return builder.String()
+}
+
+// patternMatcher applique un motif aux libellés et à la description des éléments
+type patternMatcher struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// compilePattern compile la requête d'une recherche par motif après contrôle de sa complexité
+func compilePattern(mode, query string) (*patternMatcher, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("%w: empty pattern", ErrInvalidPattern)
+	}
+	pattern := query
+	if mode == SearchModeWildcard {
+		pattern = wildcardToRegex(query)
+	}
+	re, err := regexlimit.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPattern, err)
+	}
+	return &patternMatcher{pattern: pattern, re: re}, nil
+}
+
+// ValidatePattern vérifie qu'une requête en mode regex ou wildcard se compile dans les limites de complexité
+func ValidatePattern(mode, query string) error {
+	if !isPatternMode(mode) {
+		return nil
+	}
+	_, err := compilePattern(mode, query)
+	return err
+}
+
+// match retourne la pertinence d'un élément et les champs qui correspondent au motif
+func (pm *patternMatcher) match(element *models.OntologyElement) (float64, []FieldContribution) {
+	name := FieldContribution{Field: "name", Match: MatchKindNone, Weight: patternNameRelevance}
+	for _, label := range elementLabels(element) {
+		if pm.re.MatchString(label) {
+			name = FieldContribution{Field: "name", Value: label, Match: MatchKindPattern, Similarity: 1, Weight: patternNameRelevance, Score: patternNameRelevance}
+			break
+		}
+	}
+	description := FieldContribution{Field: "description", Value: element.Description, Match: MatchKindNone, Weight: patternDescriptionRelevance}
+	if element.Description != "" && pm.re.MatchString(element.Description) {
+		description.Match, description.Similarity, description.Score = MatchKindPattern, 1, patternDescriptionRelevance
+	}
+	if name.Value == "" {
+		name.Value = element.Name
+	}
+
+	relevance := name.Score
+	if description.Score > relevance {
+		relevance = description.Score
+	}
+	return relevance, []FieldContribution{name, description}
+}
+
+// explain construit l'explication d'un résultat retenu par motif
+func (pm *patternMatcher) explain(opts SearchOptions, element *models.OntologyElement, relevance float64) *Explanation {
+	_, fields := pm.match(element)
+	return &Explanation{
+		Relevance:    relevance,
+		Threshold:    relevanceThreshold,
+		Formula:      fmt.Sprintf("pattern %s: %.1f when a name matches, %.1f when only the description matches", pm.pattern, patternNameRelevance, patternDescriptionRelevance),
+		Source:       MatchSourcePattern,
+		MatchedQuery: opts.Query,
+		Fields:       fields,
+		MatchedTerms: []string{},
+		Filters:      appliedFilters(opts),
+	}
+}
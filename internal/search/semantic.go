@@ -0,0 +1,253 @@
+package search
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+)
+
+// Modes de recherche disponibles
+const (
+	SearchModeLexical  = "lexical"
+	SearchModeSemantic = "semantic"
+	SearchModeHybrid   = "hybrid"
+)
+
+// Seuils et pondérations propres aux modes sémantique et hybride
+const (
+	// semanticThreshold est la similarité cosinus en dessous de laquelle un élément n'est pas retenu
+	semanticThreshold = 0.1
+	// hybridLexicalWeight est la part du score lexical dans le score hybride
+	hybridLexicalWeight = 0.6
+	// hybridThreshold permet de retenir un élément sur sa seule similarité sémantique
+	hybridThreshold = 0.15
+	// trigramWeight réduit le poids des trigrammes de caractères par rapport aux mots entiers
+	trigramWeight = 0.5
+)
+
+// stopWords regroupe les mots outils français ignorés lors de la vectorisation
+var stopWords = map[string]bool{
+	"le": true, "la": true, "les": true, "un": true, "une": true, "des": true,
+	"de": true, "du": true, "au": true, "aux": true, "et": true, "ou": true,
+	"en": true, "dans": true, "par": true, "pour": true, "sur": true, "avec": true,
+	"sans": true, "sous": true, "que": true, "qui": true, "dont": true, "est": true,
+	"son": true, "sa": true, "ses": true, "leur": true, "leurs": true, "ce": true,
+	"cet": true, "cette": true, "ces": true, "il": true, "elle": true, "ils": true,
+	"elles": true, "se": true, "ne": true, "pas": true, "plus": true, "a": true,
+}
+
+// vector est un vecteur creux indexé par caractéristique (mot ou trigramme)
+type vector map[string]float64
+
+// cosine calcule la similarité cosinus entre deux vecteurs normalisés
+func cosine(a, b vector) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	similarity := 0.0
+	for feature, weight := range a {
+		similarity += weight * b[feature]
+	}
+	return math.Min(similarity, 1.0)
+}
+
+// normalize ramène un vecteur à une norme unitaire
+func (v vector) normalize() vector {
+	norm := 0.0
+	for _, weight := range v {
+		norm += weight * weight
+	}
+	if norm == 0 {
+		return v
+	}
+	norm = math.Sqrt(norm)
+	for feature := range v {
+		v[feature] /= norm
+	}
+	return v
+}
+
+// textFeatures découpe un texte en mots significatifs et en trigrammes de caractères,
+// et retourne le nombre d'occurrences pondéré de chaque caractéristique
+func textFeatures(text string) map[string]float64 {
+	features := make(map[string]float64)
+	words := strings.FieldsFunc(foldText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if len([]rune(word)) < 2 || stopWords[word] {
+			continue
+		}
+		features["w:"+word]++
+
+		// Les trigrammes rapprochent les variantes morphologiques (juridique, juridiction)
+		runes := []rune("^" + word + "$")
+		for i := 0; i+3 <= len(runes); i++ {
+			features["t:"+string(runes[i:i+3])] += trigramWeight
+		}
+	}
+	return features
+}
+
+// elementText rassemble le texte décrivant un élément : libellés, description et contextes
+func elementText(element *models.OntologyElement) string {
+	parts := make([]string, 0, len(element.Contexts)+4)
+	for _, label := range elementLabels(element) {
+		parts = append(parts, strings.ReplaceAll(label, "_", " "))
+	}
+	parts = append(parts, element.Description)
+	for _, ctx := range element.Contexts {
+		parts = append(parts, strings.Join(ctx.Before, " "), strings.Join(ctx.After, " "))
+	}
+	return strings.Join(parts, " ")
+}
+
+// SemanticIndex conserve les vecteurs TF-IDF des éléments de toutes les ontologies chargées.
+// Les fréquences documentaires sont calculées sur l'ensemble du corpus, afin que les petites
+// ontologies ne soient pas favorisées ; l'index est reconstruit dès qu'une ontologie stockée change.
+type SemanticIndex struct {
+	mutex sync.RWMutex
+	// changes compte les modifications d'ontologie notifiées ; generation est la valeur
+	// de changes relevée avant de lister les ontologies de l'index installé
+	changes    uint64
+	generation uint64
+	ontologies map[string]*models.Ontology
+	idf        map[string]float64
+	vectors    map[*models.OntologyElement]vector
+}
+
+// NewSemanticIndex crée un index sémantique vide
+func NewSemanticIndex() *SemanticIndex {
+	return &SemanticIndex{
+		ontologies: make(map[string]*models.Ontology),
+		idf:        make(map[string]float64),
+		vectors:    make(map[*models.OntologyElement]vector),
+	}
+}
+
+// invalidate note la modification d'une ontologie : les constructions commencées avant sont périmées
+func (si *SemanticIndex) invalidate(string) {
+	si.mutex.Lock()
+	si.changes++
+	si.mutex.Unlock()
+}
+
+// refresh liste les ontologies stockées et reconstruit l'index s'il ne les reflète plus.
+// Chaque construction porte la génération relevée avant la liste : une construction lente,
+// commencée avant une modification, n'écrase pas l'index plus récent d'une autre requête.
+// Les ontologies retournées sont celles de la liste, que l'index ait été reconstruit ou non.
+func (si *SemanticIndex) refresh(list func() []*models.Ontology) []*models.Ontology {
+	si.mutex.RLock()
+	generation := si.changes
+	si.mutex.RUnlock()
+	ontologies := list()
+
+	si.mutex.RLock()
+	stale := len(ontologies) != len(si.ontologies)
+	for _, onto := range ontologies {
+		if si.ontologies[onto.ID] != onto {
+			stale = true
+			break
+		}
+	}
+	si.mutex.RUnlock()
+	if !stale {
+		return ontologies
+	}
+
+	counts := make(map[*models.OntologyElement]map[string]float64)
+	documentFrequency := make(map[string]int)
+	current := make(map[string]*models.Ontology, len(ontologies))
+	for _, onto := range ontologies {
+		current[onto.ID] = onto
+		for _, element := range onto.Elements {
+			features := textFeatures(elementText(element))
+			counts[element] = features
+			for feature := range features {
+				documentFrequency[feature]++
+			}
+		}
+	}
+
+	idf := make(map[string]float64, len(documentFrequency))
+	total := float64(len(counts))
+	for feature, frequency := range documentFrequency {
+		idf[feature] = math.Log(1+total/float64(frequency)) + 1
+	}
+	vectors := make(map[*models.OntologyElement]vector, len(counts))
+	for element, features := range counts {
+		vectors[element] = weigh(idf, features)
+	}
+
+	si.mutex.Lock()
+	if generation >= si.generation {
+		si.generation = generation
+		si.ontologies, si.idf, si.vectors = current, idf, vectors
+	}
+	si.mutex.Unlock()
+	return ontologies
+}
+
+// weigh convertit des occurrences en vecteur TF-IDF normalisé, les caractéristiques absentes du corpus étant ignorées
+func weigh(idf map[string]float64, features map[string]float64) vector {
+	v := make(vector, len(features))
+	for feature, count := range features {
+		if weight, known := idf[feature]; known {
+			v[feature] = (1 + math.Log(count)) * weight
+		}
+	}
+	return v.normalize()
+}
+
+// queryVector vectorise une requête avec les pondérations du corpus
+func (si *SemanticIndex) queryVector(query string) vector {
+	si.mutex.RLock()
+	defer si.mutex.RUnlock()
+	return weigh(si.idf, textFeatures(query))
+}
+
+// vector retourne le vecteur d'un élément indexé
+func (si *SemanticIndex) vector(element *models.OntologyElement) vector {
+	si.mutex.RLock()
+	defer si.mutex.RUnlock()
+	return si.vectors[element]
+}
+
+// modeThreshold retourne le score minimal d'un résultat selon le mode de recherche
+func modeThreshold(mode string) float64 {
+	switch mode {
+	case SearchModeSemantic:
+		return semanticThreshold
+	case SearchModeHybrid:
+		return hybridThreshold
+	default:
+		return relevanceThreshold
+	}
+}
+
+// semanticDominates indique si la similarité sémantique apporte l'essentiel du score combiné
+func semanticDominates(mode string, lexical, semantic float64) bool {
+	switch mode {
+	case SearchModeSemantic:
+		return true
+	case SearchModeHybrid:
+		return (1-hybridLexicalWeight)*semantic > hybridLexicalWeight*lexical
+	default:
+		return false
+	}
+}
+
+// blendRelevance combine les scores lexical et sémantique selon le mode de recherche
+func blendRelevance(mode string, lexical, semantic float64) float64 {
+	switch mode {
+	case SearchModeSemantic:
+		return semantic
+	case SearchModeHybrid:
+		return hybridLexicalWeight*lexical + (1-hybridLexicalWeight)*semantic
+	default:
+		return lexical
+	}
+}
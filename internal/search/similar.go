@@ -0,0 +1,232 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+	"github.com/chrlesur/ontology-server/internal/storage"
+)
+
+// Pondération des signaux combinés pour les éléments similaires
+const (
+	similarTextWeight         = 0.5
+	similarNeighbourWeight    = 0.3
+	similarCoOccurrenceWeight = 0.2
+	// similarThreshold écarte les éléments dont le score combiné est négligeable
+	similarThreshold = 0.05
+)
+
+// SimilarElement est un élément proche d'un élément de référence, avec le détail des signaux qui les rapprochent
+type SimilarElement struct {
+	OntologyID   string  `json:"ontology_id"`
+	OntologyName string  `json:"ontology_name"`
+	ElementName  string  `json:"element_name"`
+	ElementType  string  `json:"element_type"`
+	Description  string  `json:"description"`
+	Score        float64 `json:"score"`
+	// DescriptionSimilarity est la similarité TF-IDF des descriptions, libellés et contextes
+	DescriptionSimilarity float64 `json:"description_similarity"`
+	// NeighbourSimilarity est l'indice de Jaccard des voisins par relation
+	NeighbourSimilarity float64 `json:"neighbour_similarity"`
+	// CoOccurrence mesure la présence dans les mêmes fichiers et les mêmes contextes
+	CoOccurrence     float64  `json:"co_occurrence"`
+	SharedNeighbours []string `json:"shared_neighbours"`
+	SharedFiles      []string `json:"shared_files"`
+}
+
+// elementProfile rassemble ce qui caractérise un élément, toutes ontologies confondues
+type elementProfile struct {
+	vectors    []vector
+	neighbours map[string]bool
+	files      map[string]bool
+	contexts   []string
+	key        string
+	label      string
+}
+
+// similarityProfiles contient les profils de tous les éléments, calculés une seule fois
+// pour un état donné des ontologies
+type similarityProfiles struct {
+	once       sync.Once
+	ontologies []*models.Ontology
+	// elements associe à chaque élément son profil, occurrences regroupe les éléments par nom normalisé
+	elements    map[*models.OntologyElement]*elementProfile
+	occurrences map[string][]*models.OntologyElement
+}
+
+// similarityIndex conserve les profils jusqu'à la prochaine modification d'une ontologie
+type similarityIndex struct {
+	mutex    sync.Mutex
+	profiles *similarityProfiles
+}
+
+// invalidate oublie les profils : ils dépendent des relations et des fréquences de toutes les ontologies
+func (si *similarityIndex) invalidate(string) {
+	si.mutex.Lock()
+	si.profiles = nil
+	si.mutex.Unlock()
+}
+
+// similarityProfiles retourne les profils des éléments, construits à la première demande qui suit une modification
+func (se *SearchEngine) similarityProfiles() *similarityProfiles {
+	se.similarity.mutex.Lock()
+	profiles := se.similarity.profiles
+	if profiles == nil {
+		profiles = &similarityProfiles{}
+		se.similarity.profiles = profiles
+	}
+	se.similarity.mutex.Unlock()
+
+	profiles.once.Do(func() {
+		profiles.ontologies = se.semantic.refresh(se.Storage.ListOntologies)
+		neighbours := se.Graph.NeighbourSets()
+		profiles.elements = make(map[*models.OntologyElement]*elementProfile)
+		profiles.occurrences = make(map[string][]*models.OntologyElement)
+		for _, onto := range profiles.ontologies {
+			for _, element := range onto.Elements {
+				p := se.profile(element, neighbours)
+				profiles.elements[element] = p
+				profiles.occurrences[p.key] = append(profiles.occurrences[p.key], element)
+			}
+		}
+	})
+	return profiles
+}
+
+// profile construit le profil d'une occurrence d'un élément
+func (se *SearchEngine) profile(element *models.OntologyElement, neighbours map[string]map[string]bool) *elementProfile {
+	p := &elementProfile{
+		vectors: []vector{se.semantic.vector(element)},
+		files:   make(map[string]bool),
+		key:     storage.NormalizeElementName(element.Name),
+		label:   normalizeSuggestKey(element.Name),
+	}
+	for _, ctx := range element.Contexts {
+		if ctx.FileID != "" {
+			p.files[ctx.FileID] = true
+		}
+		snippet, _ := contextSnippet(ctx)
+		p.contexts = append(p.contexts, normalizeSuggestKey(snippet))
+	}
+	p.neighbours = neighbours[p.key]
+	return p
+}
+
+// mergeProfiles rassemble les profils des occurrences d'un même élément dans plusieurs ontologies
+func mergeProfiles(profiles []*elementProfile) *elementProfile {
+	merged := &elementProfile{
+		neighbours: profiles[0].neighbours,
+		files:      make(map[string]bool),
+		key:        profiles[0].key,
+		label:      profiles[0].label,
+	}
+	for _, p := range profiles {
+		merged.vectors = append(merged.vectors, p.vectors...)
+		for file := range p.files {
+			merged.files[file] = true
+		}
+		merged.contexts = append(merged.contexts, p.contexts...)
+	}
+	return merged
+}
+
+// jaccard calcule l'indice de Jaccard de deux ensembles et retourne leurs éléments communs triés
+func jaccard(a, b map[string]bool) (float64, []string) {
+	shared := []string{}
+	for value := range a {
+		if b[value] {
+			shared = append(shared, value)
+		}
+	}
+	union := len(a) + len(b) - len(shared)
+	if union == 0 {
+		return 0, shared
+	}
+	sort.Strings(shared)
+	return float64(len(shared)) / float64(union), shared
+}
+
+// mentionShare retourne la part des contextes qui mentionnent le nom de l'élément
+func mentionShare(contexts []string, name string) float64 {
+	if len(contexts) == 0 || name == "" {
+		return 0
+	}
+	mentions := 0
+	for _, ctx := range contexts {
+		if strings.Contains(" "+ctx+" ", " "+name+" ") {
+			mentions++
+		}
+	}
+	return float64(mentions) / float64(len(contexts))
+}
+
+// SimilarElements retourne les éléments les plus proches d'un élément, dans toutes les ontologies chargées.
+// Le score combine la similarité des descriptions, les voisins partagés par relation et la
+// co-occurrence dans les mêmes fichiers et contextes.
+func (se *SearchEngine) SimilarElements(elementName string, limit int) ([]SimilarElement, error) {
+	profiles := se.similarityProfiles()
+
+	key := storage.NormalizeElementName(elementName)
+	occurrences := profiles.occurrences[key]
+	if len(occurrences) == 0 {
+		return nil, fmt.Errorf("element not found: %s", elementName)
+	}
+	var references []*elementProfile
+	for _, element := range occurrences {
+		references = append(references, profiles.elements[element])
+	}
+	reference := mergeProfiles(references)
+
+	similar := []SimilarElement{}
+	for _, onto := range profiles.ontologies {
+		for _, element := range onto.Elements {
+			candidate := profiles.elements[element]
+			if candidate.key == key {
+				continue
+			}
+
+			text := 0.0
+			for _, v := range reference.vectors {
+				text = math.Max(text, cosine(v, candidate.vectors[0]))
+			}
+			neighbour, sharedNeighbours := jaccard(reference.neighbours, candidate.neighbours)
+			files, sharedFiles := jaccard(reference.files, candidate.files)
+			mentions := math.Max(mentionShare(reference.contexts, candidate.label),
+				mentionShare(candidate.contexts, reference.label))
+			coOccurrence := 0.5*files + 0.5*mentions
+
+			score := similarTextWeight*text + similarNeighbourWeight*neighbour + similarCoOccurrenceWeight*coOccurrence
+			if score < similarThreshold {
+				continue
+			}
+			similar = append(similar, SimilarElement{
+				OntologyID:            onto.ID,
+				OntologyName:          onto.Name,
+				ElementName:           element.Name,
+				ElementType:           element.Type,
+				Description:           element.Description,
+				Score:                 score,
+				DescriptionSimilarity: text,
+				NeighbourSimilarity:   neighbour,
+				CoOccurrence:          coOccurrence,
+				SharedNeighbours:      sharedNeighbours,
+				SharedFiles:           sharedFiles,
+			})
+		}
+	}
+
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].Score != similar[j].Score {
+			return similar[i].Score > similar[j].Score
+		}
+		return similar[i].ElementName < similar[j].ElementName
+	})
+	if limit > 0 && len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar, nil
+}
@@ -0,0 +1,269 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+)
+
+// maxSuggestCandidates borne le nombre d'entrées examinées sous un préfixe, pour garder des temps de réponse constants
+const maxSuggestCandidates = 1000
+
+// Suggestion représente une complétion proposée pour un préfixe
+type Suggestion struct {
+	Name         string  `json:"name"`
+	Label        string  `json:"label"`
+	Type         string  `json:"type"`
+	OntologyID   string  `json:"ontology_id"`
+	OntologyName string  `json:"ontology_name"`
+	Matched      string  `json:"matched"`
+	Score        float64 `json:"score"`
+}
+
+// trieNode est un nœud de l'arbre préfixe des noms normalisés
+type trieNode struct {
+	children map[rune]*trieNode
+	entries  []*suggestEntry
+}
+
+// suggestEntry associe une clé indexée à l'élément qu'elle désigne
+type suggestEntry struct {
+	element   *models.OntologyElement
+	key       string
+	fullName  bool
+	frequency int
+}
+
+// ontologyTrie est l'arbre préfixe d'une ontologie, construit pour une version donnée de celle-ci,
+// accompagné du vocabulaire de ses libellés (mot normalisé vers fréquence) et de la clé phonétique de chaque mot
+type ontologyTrie struct {
+	ontology  *models.Ontology
+	root      *trieNode
+	words     map[string]int
+	phonetics map[string]string
+}
+
+// SuggestIndex maintient un arbre préfixe par ontologie sur les noms et alias des éléments
+type SuggestIndex struct {
+	mutex sync.RWMutex
+	tries map[string]*ontologyTrie
+}
+
+// NewSuggestIndex crée un index de complétion vide
+func NewSuggestIndex() *SuggestIndex {
+	return &SuggestIndex{tries: make(map[string]*ontologyTrie)}
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: make(map[rune]*trieNode)}
+}
+
+func (n *trieNode) insert(key string, entry *suggestEntry) {
+	node := n
+	for _, r := range key {
+		child, exists := node.children[r]
+		if !exists {
+			child = newTrieNode()
+			node.children[r] = child
+		}
+		node = child
+	}
+	node.entries = append(node.entries, entry)
+}
+
+func (n *trieNode) find(prefix string) *trieNode {
+	node := n
+	for _, r := range prefix {
+		child, exists := node.children[r]
+		if !exists {
+			return nil
+		}
+		node = child
+	}
+	return node
+}
+
+// collect parcourt le sous-arbre en largeur, niveau par niveau, et retient les entrées acceptées par accept.
+// Les clés les plus courtes, qui couvrent le mieux le préfixe, sont ainsi examinées en premier ; le parcours
+// s'arrête à la fin du niveau où limit entrées sont atteintes, sans couper un niveau dans l'ordre alphabétique.
+func (n *trieNode) collect(limit int, accept func(*suggestEntry) bool) []*suggestEntry {
+	var entries []*suggestEntry
+	level := []*trieNode{n}
+	for len(level) > 0 && len(entries) < limit {
+		var next []*trieNode
+		for _, node := range level {
+			for _, entry := range node.entries {
+				if accept(entry) {
+					entries = append(entries, entry)
+				}
+			}
+			for _, child := range node.children {
+				next = append(next, child)
+			}
+		}
+		level = next
+	}
+	return entries
+}
+
+// normalizeSuggestKey ramène un nom ou un préfixe à la forme indexée : minuscules, sans accents, mots séparés par des espaces
+func normalizeSuggestKey(name string) string {
+	return strings.Join(strings.Fields(foldText(strings.ReplaceAll(name, "_", " "))), " ")
+}
+
+// elementLabels retourne les libellés sous lesquels un élément peut être retrouvé :
+// son nom, son nom d'origine et ses libellés alternatifs
+func elementLabels(element *models.OntologyElement) []string {
+	labels := []string{element.Name}
+	if element.OriginalName != "" && element.OriginalName != element.Name {
+		labels = append(labels, element.OriginalName)
+	}
+	for _, label := range element.AltLabels {
+		if label != "" && label != element.Name {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// buildOntologyTrie indexe chaque libellé complet ainsi que chacun de ses suffixes commençant à un mot
+func buildOntologyTrie(onto *models.Ontology) *ontologyTrie {
+	root := newTrieNode()
+	words := make(map[string]int)
+	for _, element := range onto.Elements {
+		frequency := len(element.Positions)
+		if frequency == 0 {
+			frequency = len(element.Contexts)
+		}
+		for _, label := range elementLabels(element) {
+			key := normalizeSuggestKey(label)
+			if key == "" {
+				continue
+			}
+			labelWords := strings.Split(key, " ")
+			for i, word := range labelWords {
+				words[word] += max(frequency, 1)
+				suffix := strings.Join(labelWords[i:], " ")
+				root.insert(suffix, &suggestEntry{
+					element:   element,
+					key:       suffix,
+					fullName:  i == 0,
+					frequency: frequency,
+				})
+			}
+		}
+	}
+	phonetics := make(map[string]string, len(words))
+	for word := range words {
+		phonetics[word] = phoneticKey(word)
+	}
+	return &ontologyTrie{ontology: onto, root: root, words: words, phonetics: phonetics}
+}
+
+// refresh reconstruit les arbres des ontologies ajoutées ou remplacées et oublie celles qui ont été supprimées
+func (si *SuggestIndex) refresh(ontologies []*models.Ontology) {
+	si.mutex.RLock()
+	upToDate := len(si.tries) == len(ontologies)
+	for _, onto := range ontologies {
+		if trie, exists := si.tries[onto.ID]; !exists || trie.ontology != onto {
+			upToDate = false
+			break
+		}
+	}
+	si.mutex.RUnlock()
+	if upToDate {
+		return
+	}
+
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+
+	current := make(map[string]bool, len(ontologies))
+	for _, onto := range ontologies {
+		current[onto.ID] = true
+		if trie, exists := si.tries[onto.ID]; !exists || trie.ontology != onto {
+			si.tries[onto.ID] = buildOntologyTrie(onto)
+		}
+	}
+	for id := range si.tries {
+		if !current[id] {
+			delete(si.tries, id)
+		}
+	}
+}
+
+// Suggest retourne les complétions les mieux classées pour un préfixe, éventuellement restreintes à certaines ontologies et types
+func (se *SearchEngine) Suggest(prefix string, ontologyIDs []string, elementTypes []string, limit int) []Suggestion {
+	key := normalizeSuggestKey(prefix)
+	if key == "" || limit <= 0 {
+		return []Suggestion{}
+	}
+
+	se.suggestions.refresh(se.Storage.ListOntologies())
+
+	se.suggestions.mutex.RLock()
+	defer se.suggestions.mutex.RUnlock()
+
+	best := make(map[*models.OntologyElement]Suggestion)
+	for id, trie := range se.suggestions.tries {
+		if !matchesAnyValue([]string{id}, ontologyIDs) {
+			continue
+		}
+		node := trie.root.find(key)
+		if node == nil {
+			continue
+		}
+		// Comme dans la recherche, un type parent inclut ses sous-types
+		var types []string
+		if len(elementTypes) > 0 {
+			types = se.Storage.TypeHierarchy(trie.ontology).Expand(elementTypes)
+		}
+		accept := func(entry *suggestEntry) bool {
+			return matchesElementType(entry.element.Type, types)
+		}
+		for _, entry := range node.collect(maxSuggestCandidates, accept) {
+			score := suggestionScore(key, entry)
+			if existing, exists := best[entry.element]; exists && existing.Score >= score {
+				continue
+			}
+			best[entry.element] = Suggestion{
+				Name:         entry.element.Name,
+				Label:        strings.ReplaceAll(entry.element.Name, "_", " "),
+				Type:         entry.element.Type,
+				OntologyID:   trie.ontology.ID,
+				OntologyName: trie.ontology.Name,
+				Matched:      entry.key,
+				Score:        score,
+			}
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(best))
+	for _, s := range best {
+		suggestions = append(suggestions, s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Name < suggestions[j].Name
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// suggestionScore favorise les complétions proches du préfixe saisi, portant sur le nom complet, et les éléments fréquents
+func suggestionScore(prefix string, entry *suggestEntry) float64 {
+	coverage := float64(len([]rune(prefix))) / float64(len([]rune(entry.key)))
+	if !entry.fullName {
+		coverage *= 0.8
+	}
+	score := coverage + 0.1*math.Log1p(float64(entry.frequency))
+	return math.Round(score*1000) / 1000
+}
@@ -0,0 +1,151 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxQueryExpansions borne le nombre de requêtes dérivées d'une requête par les synonymes
+const maxQueryExpansions = 10
+
+// SynonymSet est un ensemble de termes équivalents, global ou propre à une ontologie
+type SynonymSet struct {
+	OntologyID string   `json:"ontology_id,omitempty"`
+	Terms      []string `json:"terms"`
+}
+
+// SynonymDictionary conserve les ensembles de synonymes utilisés pour étendre les requêtes.
+// La portée "" regroupe les synonymes globaux, les autres portées sont des identifiants d'ontologie.
+type SynonymDictionary struct {
+	mutex sync.RWMutex
+	sets  map[string][]SynonymSet
+	// version est incrémentée à chaque modification, pour invalider les résultats mis en cache
+	version uint64
+}
+
+// NewSynonymDictionary crée un dictionnaire de synonymes vide
+func NewSynonymDictionary() *SynonymDictionary {
+	return &SynonymDictionary{sets: make(map[string][]SynonymSet)}
+}
+
+// Add ajoute un ensemble de synonymes dans la portée indiquée
+func (sd *SynonymDictionary) Add(ontologyID string, terms []string) error {
+	var cleaned []string
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		key := normalizeSuggestKey(term)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, term)
+	}
+	if len(cleaned) < 2 {
+		return fmt.Errorf("a synonym set needs at least two distinct terms")
+	}
+
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+	sd.sets[ontologyID] = append(sd.sets[ontologyID], SynonymSet{OntologyID: ontologyID, Terms: cleaned})
+	sd.version++
+	return nil
+}
+
+// Load ajoute plusieurs ensembles de synonymes et retourne le nombre d'ensembles retenus
+func (sd *SynonymDictionary) Load(ontologyID string, sets [][]string) int {
+	loaded := 0
+	for _, terms := range sets {
+		if err := sd.Add(ontologyID, terms); err == nil {
+			loaded++
+		}
+	}
+	return loaded
+}
+
+// Sets retourne les ensembles de synonymes d'une portée
+func (sd *SynonymDictionary) Sets(ontologyID string) []SynonymSet {
+	sd.mutex.RLock()
+	defer sd.mutex.RUnlock()
+	return append([]SynonymSet{}, sd.sets[ontologyID]...)
+}
+
+// Clear supprime tous les ensembles de synonymes d'une portée
+func (sd *SynonymDictionary) Clear(ontologyID string) {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+	delete(sd.sets, ontologyID)
+	sd.version++
+}
+
+// Version retourne le numéro de version courant du dictionnaire
+func (sd *SynonymDictionary) Version() uint64 {
+	sd.mutex.RLock()
+	defer sd.mutex.RUnlock()
+	return sd.version
+}
+
+// Expand retourne les requêtes équivalentes à query pour une ontologie, en
+// combinant les synonymes globaux et ceux de l'ontologie. La requête entière
+// est d'abord remplacée par ses synonymes, puis chacun de ses mots.
+func (sd *SynonymDictionary) Expand(query, ontologyID string) []string {
+	normalized := normalizeSuggestKey(query)
+	if normalized == "" {
+		return nil
+	}
+
+	sd.mutex.RLock()
+	defer sd.mutex.RUnlock()
+
+	sets := append(append([]SynonymSet{}, sd.sets[""]...), sd.sets[ontologyID]...)
+	if len(sets) == 0 {
+		return nil
+	}
+
+	seen := map[string]bool{normalized: true}
+	var expansions []string
+	add := func(expansion string) {
+		key := normalizeSuggestKey(expansion)
+		if !seen[key] && len(expansions) < maxQueryExpansions {
+			seen[key] = true
+			expansions = append(expansions, expansion)
+		}
+	}
+
+	for _, set := range sets {
+		if synonymIndex(set, normalized) >= 0 {
+			for _, term := range set.Terms {
+				add(term)
+			}
+		}
+	}
+
+	words := strings.Split(normalized, " ")
+	if len(words) > 1 {
+		for i, word := range words {
+			for _, set := range sets {
+				if synonymIndex(set, word) < 0 {
+					continue
+				}
+				for _, term := range set.Terms {
+					replaced := append([]string(nil), words...)
+					replaced[i] = normalizeSuggestKey(term)
+					add(strings.Join(replaced, " "))
+				}
+			}
+		}
+	}
+
+	return expansions
+}
+
+// synonymIndex retourne la position du terme normalisé dans l'ensemble, ou -1
+func synonymIndex(set SynonymSet, normalized string) int {
+	for i, term := range set.Terms {
+		if normalizeSuggestKey(term) == normalized {
+			return i
+		}
+	}
+	return -1
+}
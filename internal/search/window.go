@@ -0,0 +1,210 @@
+package search
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrlesur/ontology-server/internal/logger"
+	"github.com/chrlesur/ontology-server/internal/models"
+)
+
+// defaultSourceCacheBytes borne la mémoire occupée par les fichiers source conservés
+const defaultSourceCacheBytes = 64 << 20
+
+// sourceCache conserve les mots des fichiers source déjà lus, tant qu'ils ne sont pas modifiés.
+// C'est un cache LRU borné en octets : les fichiers les moins récemment lus sont oubliés en premier.
+type sourceCache struct {
+	mutex    sync.Mutex
+	maxBytes int
+	bytes    int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type sourceEntry struct {
+	path    string
+	modTime time.Time
+	words   []string
+	size    int
+}
+
+func newSourceCache(maxBytes int) *sourceCache {
+	return &sourceCache{maxBytes: maxBytes, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// words retourne le texte d'un fichier source découpé en mots
+func (sc *sourceCache) words(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat source file: %w", err)
+	}
+
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	if element, exists := sc.entries[path]; exists {
+		entry := element.Value.(*sourceEntry)
+		if entry.modTime.Equal(info.ModTime()) {
+			sc.order.MoveToFront(element)
+			return entry.words, nil
+		}
+		sc.remove(element)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source file: %w", err)
+	}
+
+	words := strings.Fields(string(data))
+	// Les mots partagent le texte du fichier ; chacun ajoute son en-tête de chaîne
+	entry := &sourceEntry{path: path, modTime: info.ModTime(), words: words, size: len(path) + len(data) + wordOverhead*len(words)}
+	if entry.size <= sc.maxBytes {
+		sc.entries[path] = sc.order.PushFront(entry)
+		sc.bytes += entry.size
+		for sc.bytes > sc.maxBytes {
+			sc.remove(sc.order.Back())
+		}
+	}
+	return words, nil
+}
+
+// remove oublie un fichier source
+func (sc *sourceCache) remove(element *list.Element) {
+	entry := sc.order.Remove(element).(*sourceEntry)
+	delete(sc.entries, entry.path)
+	sc.bytes -= entry.size
+}
+
+// sourceReader lit les fichiers source pour une seule requête : chaque fichier n'est
+// examiné qu'une fois, quel que soit le nombre de contextes et de résultats qui y renvoient
+type sourceReader struct {
+	cache  *sourceCache
+	logger *logger.Logger
+	files  map[string]sourceRead
+}
+
+type sourceRead struct {
+	words []string
+	err   error
+}
+
+// sourceReader prépare la lecture des fichiers source d'une requête.
+// Un lecteur n'est pas partagé entre goroutines.
+func (se *SearchEngine) sourceReader() *sourceReader {
+	return &sourceReader{cache: se.sources, logger: se.Logger, files: make(map[string]sourceRead)}
+}
+
+// words retourne les mots d'un fichier source, lus au plus une fois par requête
+func (sr *sourceReader) words(path string) ([]string, error) {
+	read, exists := sr.files[path]
+	if !exists {
+		read.words, read.err = sr.cache.words(path)
+		if read.err != nil {
+			sr.logger.Warning(fmt.Sprintf("Cannot widen contexts from %s: %v", path, read.err))
+		}
+		sr.files[path] = read
+	}
+	return read.words, read.err
+}
+
+// contextWindows ramène chaque contexte à size mots avant et après l'élément.
+// Les contextes stockés sont tronqués ; lorsqu'ils sont plus courts que la
+// fenêtre demandée et que le document source est disponible, la fenêtre est
+// reconstruite à partir du texte source.
+func (sr *sourceReader) contextWindows(contexts []models.JSONContext, source *models.SourceMetadata, size int) []models.JSONContext {
+	windows := make([]models.JSONContext, len(contexts))
+	for i, ctx := range contexts {
+		windows[i] = sr.contextWindow(ctx, source, size)
+	}
+	return windows
+}
+
+func (sr *sourceReader) contextWindow(ctx models.JSONContext, source *models.SourceMetadata, size int) models.JSONContext {
+	if len(ctx.Before) < size || len(ctx.After) < size {
+		if widened, ok := sr.widenFromSource(ctx, source, size); ok {
+			return widened
+		}
+	}
+
+	ctx.Before = lastWords(ctx.Before, size)
+	ctx.After = firstWords(ctx.After, size)
+	return ctx
+}
+
+// widenFromSource reconstruit la fenêtre d'un contexte à partir du fichier source.
+// La reconstruction est abandonnée si les mots du fichier ne correspondent pas
+// à ceux stockés, ce qui signale un fichier modifié depuis l'extraction.
+func (sr *sourceReader) widenFromSource(ctx models.JSONContext, source *models.SourceMetadata, size int) (models.JSONContext, bool) {
+	if source == nil {
+		return ctx, false
+	}
+	fileInfo, exists := source.Files[ctx.FileID]
+	if !exists || fileInfo.SourceFile == "" {
+		return ctx, false
+	}
+
+	path := filepath.Join(fileInfo.Directory, fileInfo.SourceFile)
+	words, err := sr.words(path)
+	if err != nil {
+		return ctx, false
+	}
+
+	length := ctx.Length
+	if length <= 0 {
+		length = 1
+	}
+	start := ctx.FilePosition
+	end := start + length
+	if start < 0 || end > len(words) {
+		return ctx, false
+	}
+
+	if !matchesSource(ctx, words, start, end) {
+		sr.logger.Warning(fmt.Sprintf("Source file %s does not match stored context of %s at position %d", path, ctx.Element, start))
+		return ctx, false
+	}
+
+	ctx.Before = append([]string(nil), words[max(0, start-size):start]...)
+	ctx.After = append([]string(nil), words[end:min(len(words), end+size)]...)
+	return ctx, true
+}
+
+// matchesSource vérifie que le fichier source contient encore, à la position stockée,
+// l'élément du contexte et les mots qui l'entourent.
+// Les mots de l'élément sont comparés sans casse ni accents et peuvent porter une
+// flexion ou une ponctuation finale ("Joueuse_Internationale" pour "joueuses internationales,").
+func matchesSource(ctx models.JSONContext, words []string, start, end int) bool {
+	if len(ctx.Before) > 0 && (start == 0 || words[start-1] != ctx.Before[len(ctx.Before)-1]) {
+		return false
+	}
+	if len(ctx.After) > 0 && (end == len(words) || words[end] != ctx.After[0]) {
+		return false
+	}
+	tokens := strings.Fields(normalizeSuggestKey(ctx.Element))
+	for i := 0; i < len(tokens) && start+i < end; i++ {
+		if !strings.HasPrefix(normalizeSuggestKey(words[start+i]), tokens[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func lastWords(words []string, n int) []string {
+	if len(words) <= n {
+		return words
+	}
+	return words[len(words)-n:]
+}
+
+func firstWords(words []string, n int) []string {
+	if len(words) <= n {
+		return words
+	}
+	return words[:n]
+}
@@ -0,0 +1,322 @@
+package sparql
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrlesur/ontology-server/internal/graph"
+	"github.com/chrlesur/ontology-server/internal/models"
+)
+
+// Espaces de noms utilisés pour exposer les ontologies en RDF
+const (
+	// BaseIRI préfixe les IRI des ontologies, éléments, fichiers et relations
+	BaseIRI = "http://ontology-server/"
+	// SchemaIRI est le vocabulaire du serveur (classes et propriétés)
+	SchemaIRI = BaseIRI + "schema#"
+	// RelationIRI préfixe les types de relation, utilisés comme prédicats
+	RelationIRI = BaseIRI + "relation/"
+
+	rdfIRI  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	rdfsIRI = "http://www.w3.org/2000/01/rdf-schema#"
+	xsdIRI  = "http://www.w3.org/2001/XMLSchema#"
+	skosIRI = "http://www.w3.org/2004/02/skos/core#"
+)
+
+// defaultPrefixes sont les préfixes utilisables sans déclaration PREFIX
+var defaultPrefixes = map[string]string{
+	"rdf":  rdfIRI,
+	"rdfs": rdfsIRI,
+	"xsd":  xsdIRI,
+	"skos": skosIRI,
+	"os":   SchemaIRI,
+	"rel":  RelationIRI,
+}
+
+// TermKind est la nature d'un terme RDF
+type TermKind int
+
+const (
+	KindIRI TermKind = iota
+	KindLiteral
+	KindBlank
+)
+
+// Term est un terme RDF : IRI, littéral (avec type de donnée ou langue) ou nœud anonyme
+type Term struct {
+	Kind     TermKind
+	Value    string
+	Datatype string
+	Lang     string
+}
+
+// IRI construit un terme IRI
+func IRI(value string) Term {
+	return Term{Kind: KindIRI, Value: value}
+}
+
+// Literal construit un littéral simple
+func Literal(value string) Term {
+	return Term{Kind: KindLiteral, Value: value}
+}
+
+// TypedLiteral construit un littéral typé
+func TypedLiteral(value, datatype string) Term {
+	return Term{Kind: KindLiteral, Value: value, Datatype: datatype}
+}
+
+// String retourne le terme au format N-Triples, qui sert aussi de clé d'index
+func (t Term) String() string {
+	switch t.Kind {
+	case KindIRI:
+		return "<" + t.Value + ">"
+	case KindBlank:
+		return "_:" + t.Value
+	}
+	s := quoteLiteral(t.Value)
+	if t.Lang != "" {
+		return s + "@" + t.Lang
+	}
+	if t.Datatype != "" {
+		return s + "^^<" + t.Datatype + ">"
+	}
+	return s
+}
+
+// quoteLiteral met un littéral entre guillemets selon la grammaire N-Triples : guillemets, barre oblique
+// inverse et fins de ligne par leur échappement court, autres caractères de contrôle en \uXXXX
+func quoteLiteral(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || (r >= 0x7f && r < 0xa0) {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Triple est un triplet RDF
+type Triple struct {
+	Subject   Term
+	Predicate Term
+	Object    Term
+}
+
+// String retourne le triplet au format N-Triples
+func (t Triple) String() string {
+	return fmt.Sprintf("%s %s %s .", t.Subject, t.Predicate, t.Object)
+}
+
+// Dataset est un ensemble de triplets indexé par sujet, prédicat et objet
+type Dataset struct {
+	triples     []Triple
+	bySubject   map[string][]int
+	byPredicate map[string][]int
+	byObject    map[string][]int
+	seen        map[string]bool
+}
+
+func newDataset() *Dataset {
+	return &Dataset{
+		bySubject:   make(map[string][]int),
+		byPredicate: make(map[string][]int),
+		byObject:    make(map[string][]int),
+		seen:        make(map[string]bool),
+	}
+}
+
+// add ajoute un triplet s'il n'est pas déjà présent
+func (d *Dataset) add(subject, predicate, object Term) {
+	t := Triple{subject, predicate, object}
+	key := t.String()
+	if d.seen[key] {
+		return
+	}
+	d.seen[key] = true
+	i := len(d.triples)
+	d.triples = append(d.triples, t)
+	d.bySubject[subject.String()] = append(d.bySubject[subject.String()], i)
+	d.byPredicate[predicate.String()] = append(d.byPredicate[predicate.String()], i)
+	d.byObject[object.String()] = append(d.byObject[object.String()], i)
+}
+
+// Len retourne le nombre de triplets
+func (d *Dataset) Len() int {
+	return len(d.triples)
+}
+
+// candidates retourne les indices des triplets compatibles avec les termes connus d'un motif,
+// en utilisant l'index le plus sélectif
+func (d *Dataset) candidates(subject, predicate, object *Term) []int {
+	var best []int
+	found := false
+	for _, lookup := range []struct {
+		term  *Term
+		index map[string][]int
+	}{{subject, d.bySubject}, {object, d.byObject}, {predicate, d.byPredicate}} {
+		if lookup.term == nil {
+			continue
+		}
+		indices := lookup.index[lookup.term.String()]
+		if !found || len(indices) < len(best) {
+			best, found = indices, true
+		}
+	}
+	if !found {
+		all := make([]int, len(d.triples))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+	return best
+}
+
+// ontologyIRI, elementIRI, fileIRI et relationTypeIRI construisent les IRI des ressources exposées
+func ontologyIRI(ontologyID string) Term {
+	return IRI(BaseIRI + "ontology/" + url.PathEscape(ontologyID))
+}
+
+func elementIRI(ontologyID, node string) Term {
+	return IRI(BaseIRI + "ontology/" + url.PathEscape(ontologyID) + "/element/" + url.PathEscape(node))
+}
+
+func fileIRI(fileID string) Term {
+	return IRI(BaseIRI + "file/" + url.PathEscape(fileID))
+}
+
+func relationTypeIRI(relationType string) Term {
+	return IRI(RelationIRI + url.PathEscape(relationType))
+}
+
+func schema(name string) Term {
+	return IRI(SchemaIRI + name)
+}
+
+var (
+	rdfType      = IRI(rdfIRI + "type")
+	rdfStatement = IRI(rdfIRI + "Statement")
+	rdfSubject   = IRI(rdfIRI + "subject")
+	rdfPredicate = IRI(rdfIRI + "predicate")
+	rdfObject    = IRI(rdfIRI + "object")
+	rdfsLabel    = IRI(rdfsIRI + "label")
+	rdfsComment  = IRI(rdfsIRI + "comment")
+	skosAltLabel = IRI(skosIRI + "altLabel")
+)
+
+// buildDataset expose les ontologies en triplets :
+//   - chaque ontologie est un os:Ontology (rdfs:label, os:filename, os:importedAt, os:hasFile) ;
+//   - chaque élément est un os:Element (rdfs:label, os:elementType, rdfs:comment, skos:altLabel,
+//     os:ontology, os:mentionedIn vers les fichiers sources de ses contextes) ;
+//   - chaque fichier source est un os:SourceFile (os:path, os:sha256) ;
+//   - chaque relation, déclarée ou déduite, est un triplet <source> rel:<type> <cible>, réifié par
+//     un rdf:Statement portant sa description, son ontologie et os:inferred.
+func buildDataset(ontologies []*models.Ontology, g *graph.Graph) *Dataset {
+	sort.Slice(ontologies, func(i, j int) bool {
+		return ontologies[i].ID < ontologies[j].ID
+	})
+
+	d := newDataset()
+	for _, onto := range ontologies {
+		ontology := ontologyIRI(onto.ID)
+		d.add(ontology, rdfType, schema("Ontology"))
+		d.add(ontology, schema("id"), Literal(onto.ID))
+		if onto.Name != "" {
+			d.add(ontology, rdfsLabel, Literal(onto.Name))
+		}
+		if onto.Filename != "" {
+			d.add(ontology, schema("filename"), Literal(onto.Filename))
+		}
+		if !onto.ImportedAt.IsZero() {
+			d.add(ontology, schema("importedAt"), TypedLiteral(onto.ImportedAt.Format(time.RFC3339), xsdIRI+"dateTime"))
+		}
+		if onto.Source != nil {
+			for fileID, info := range onto.Source.Files {
+				file := fileIRI(fileID)
+				d.add(ontology, schema("hasFile"), file)
+				d.add(file, rdfType, schema("SourceFile"))
+				d.add(file, schema("path"), Literal(info.SourceFile))
+				if info.SHA256Hash != "" {
+					d.add(file, schema("sha256"), Literal(info.SHA256Hash))
+				}
+			}
+		}
+
+		// labelled contient les nœuds qui portent déjà un libellé : les éléments déclarés,
+		// puis les extrémités de relation non déclarées au fur et à mesure de leur exposition
+		labelled := make(map[string]bool, len(onto.Elements))
+		for _, element := range onto.Elements {
+			labelled[graph.CanonicalID(element.Name)] = true
+			node := elementIRI(onto.ID, graph.CanonicalID(element.Name))
+			d.add(node, rdfType, schema("Element"))
+			d.add(node, rdfsLabel, Literal(element.Name))
+			d.add(node, schema("ontology"), ontology)
+			for _, t := range strings.Split(element.Type, "/") {
+				if t = strings.TrimSpace(t); t != "" {
+					d.add(node, schema("elementType"), Literal(t))
+				}
+			}
+			if element.Description != "" {
+				d.add(node, rdfsComment, Literal(element.Description))
+			}
+			for _, label := range element.AltLabels {
+				d.add(node, skosAltLabel, Literal(label))
+			}
+			for _, ctx := range element.Contexts {
+				if ctx.FileID != "" {
+					d.add(node, schema("mentionedIn"), fileIRI(ctx.FileID))
+				}
+			}
+		}
+
+		for i, edge := range g.OntologyEdges(onto.ID) {
+			source, target := elementIRI(onto.ID, edge.Source), elementIRI(onto.ID, edge.Target)
+			predicate := relationTypeIRI(edge.Type)
+			// Les extrémités qui ne sont pas des éléments déclarés reçoivent au moins un libellé
+			for _, endpoint := range []struct {
+				node Term
+				id   string
+			}{{source, edge.Source}, {target, edge.Target}} {
+				if !labelled[endpoint.id] {
+					labelled[endpoint.id] = true
+					d.add(endpoint.node, rdfsLabel, Literal(g.Label(onto.ID, endpoint.id)))
+					d.add(endpoint.node, schema("ontology"), ontology)
+				}
+			}
+			d.add(source, predicate, target)
+
+			statement := IRI(fmt.Sprintf("%sontology/%s/relation/%d", BaseIRI, url.PathEscape(onto.ID), i))
+			d.add(statement, rdfType, rdfStatement)
+			d.add(statement, rdfSubject, source)
+			d.add(statement, rdfPredicate, predicate)
+			d.add(statement, rdfObject, target)
+			d.add(statement, schema("ontology"), ontology)
+			d.add(statement, schema("inferred"), TypedLiteral(strconv.FormatBool(edge.Inferred()), xsdIRI+"boolean"))
+			if edge.Relation.Description != "" {
+				d.add(statement, rdfsComment, Literal(edge.Relation.Description))
+			}
+		}
+	}
+	return d
+}
@@ -0,0 +1,506 @@
+package sparql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxSteps borne le nombre de solutions intermédiaires explorées par une requête
+const maxSteps = 1000000
+
+// ErrQueryTooComplex signale une requête dont l'évaluation dépasse le budget d'exploration
+var ErrQueryTooComplex = errors.New("query exceeds the evaluation budget")
+
+// errExpression signale une erreur d'évaluation d'une expression : le FILTER concerné est alors faux
+var errExpression = errors.New("expression error")
+
+// binding associe des termes aux variables d'une solution
+type binding map[string]Term
+
+func (b binding) extend(variable string, term Term) binding {
+	extended := make(binding, len(b)+1)
+	for k, v := range b {
+		extended[k] = v
+	}
+	extended[variable] = term
+	return extended
+}
+
+// expression est une expression de FILTER ou d'ORDER BY
+type expression interface {
+	eval(b binding) (Term, error)
+}
+
+type variableExpr string
+
+func (e variableExpr) eval(b binding) (Term, error) {
+	if term, exists := b[string(e)]; exists {
+		return term, nil
+	}
+	return Term{}, errExpression
+}
+
+type constantExpr struct {
+	term Term
+}
+
+func (e constantExpr) eval(binding) (Term, error) {
+	return e.term, nil
+}
+
+var (
+	trueTerm  = TypedLiteral("true", xsdIRI+"boolean")
+	falseTerm = TypedLiteral("false", xsdIRI+"boolean")
+)
+
+func boolTerm(value bool) Term {
+	if value {
+		return trueTerm
+	}
+	return falseTerm
+}
+
+// effectiveBoolean calcule la valeur booléenne effective d'un terme
+func effectiveBoolean(term Term) (bool, error) {
+	if term.Kind != KindLiteral {
+		return false, errExpression
+	}
+	switch term.Datatype {
+	case xsdIRI + "boolean":
+		return term.Value == "true" || term.Value == "1", nil
+	case xsdIRI + "integer", xsdIRI + "decimal", xsdIRI + "double":
+		f, err := strconv.ParseFloat(term.Value, 64)
+		return err == nil && f != 0, nil
+	}
+	return term.Value != "", nil
+}
+
+type notExpr struct {
+	operand expression
+}
+
+func (e notExpr) eval(b binding) (Term, error) {
+	term, err := e.operand.eval(b)
+	if err != nil {
+		return Term{}, err
+	}
+	value, err := effectiveBoolean(term)
+	if err != nil {
+		return Term{}, err
+	}
+	return boolTerm(!value), nil
+}
+
+type andExpr struct {
+	left, right expression
+}
+
+// eval suit la logique à trois valeurs de SPARQL : une erreur combinée à faux donne faux
+func (e andExpr) eval(b binding) (Term, error) {
+	left, leftErr := evalBoolean(e.left, b)
+	right, rightErr := evalBoolean(e.right, b)
+	switch {
+	case leftErr == nil && rightErr == nil:
+		return boolTerm(left && right), nil
+	case (leftErr == nil && !left) || (rightErr == nil && !right):
+		return falseTerm, nil
+	}
+	return Term{}, errExpression
+}
+
+type orExpr struct {
+	left, right expression
+}
+
+func (e orExpr) eval(b binding) (Term, error) {
+	left, leftErr := evalBoolean(e.left, b)
+	right, rightErr := evalBoolean(e.right, b)
+	switch {
+	case leftErr == nil && rightErr == nil:
+		return boolTerm(left || right), nil
+	case (leftErr == nil && left) || (rightErr == nil && right):
+		return trueTerm, nil
+	}
+	return Term{}, errExpression
+}
+
+func evalBoolean(expr expression, b binding) (bool, error) {
+	term, err := expr.eval(b)
+	if err != nil {
+		return false, err
+	}
+	return effectiveBoolean(term)
+}
+
+type compareExpr struct {
+	operator    string
+	left, right expression
+}
+
+func (e compareExpr) eval(b binding) (Term, error) {
+	left, err := e.left.eval(b)
+	if err != nil {
+		return Term{}, err
+	}
+	right, err := e.right.eval(b)
+	if err != nil {
+		return Term{}, err
+	}
+
+	if e.operator == "=" || e.operator == "!=" {
+		equal := termsEqual(left, right)
+		return boolTerm(equal == (e.operator == "=")), nil
+	}
+	if left.Kind != KindLiteral || right.Kind != KindLiteral {
+		return Term{}, errExpression
+	}
+	c := compareLiterals(left, right)
+	switch e.operator {
+	case "<":
+		return boolTerm(c < 0), nil
+	case "<=":
+		return boolTerm(c <= 0), nil
+	case ">":
+		return boolTerm(c > 0), nil
+	}
+	return boolTerm(c >= 0), nil
+}
+
+// numeric retourne la valeur d'un littéral numérique
+func numeric(term Term) (float64, bool) {
+	switch term.Datatype {
+	case xsdIRI + "integer", xsdIRI + "decimal", xsdIRI + "double":
+		f, err := strconv.ParseFloat(term.Value, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// termsEqual compare deux termes, les littéraux numériques par valeur
+func termsEqual(a, b Term) bool {
+	if x, ok := numeric(a); ok {
+		if y, ok := numeric(b); ok {
+			return x == y
+		}
+	}
+	return a == b
+}
+
+// compareLiterals ordonne deux littéraux, numériquement si les deux sont des nombres
+func compareLiterals(a, b Term) int {
+	if x, ok := numeric(a); ok {
+		if y, ok := numeric(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(a.Value, b.Value)
+}
+
+// functionArity donne le nombre minimal et maximal d'arguments des fonctions prises en charge
+var functionArity = map[string][2]int{
+	"regex":     {2, 3},
+	"contains":  {2, 2},
+	"strstarts": {2, 2},
+	"strends":   {2, 2},
+	"lcase":     {1, 1},
+	"ucase":     {1, 1},
+	"str":       {1, 1},
+	"lang":      {1, 1},
+	"bound":     {1, 1},
+	"isiri":     {1, 1},
+	"isuri":     {1, 1},
+	"isliteral": {1, 1},
+}
+
+type functionExpr struct {
+	name string
+	args []expression
+	// pattern est l'expression régulière précompilée d'un regex() à motif constant
+	pattern *regexp.Regexp
+}
+
+func newFunction(name string, args []expression) (expression, error) {
+	f := functionExpr{name: name, args: args}
+	if name == "regex" {
+		pattern, isConstant := args[1].(constantExpr)
+		flags := ""
+		if len(args) == 3 {
+			constant, ok := args[2].(constantExpr)
+			if !ok {
+				return nil, fmt.Errorf("regex flags must be a constant")
+			}
+			flags = constant.term.Value
+		}
+		if isConstant {
+			re, err := compileRegex(pattern.term.Value, flags)
+			if err != nil {
+				return nil, err
+			}
+			f.pattern = re
+		}
+	}
+	return f, nil
+}
+
+// compileRegex compile un motif regex() avec ses options ; seule l'option "i" est prise en charge
+func compileRegex(pattern, flags string) (*regexp.Regexp, error) {
+	for _, flag := range flags {
+		if flag != 'i' {
+			return nil, fmt.Errorf("unsupported regex flag %q", flag)
+		}
+	}
+	if flags != "" {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %v", pattern, err)
+	}
+	return re, nil
+}
+
+func (f functionExpr) eval(b binding) (Term, error) {
+	if f.name == "bound" {
+		_, exists := b[string(f.args[0].(variableExpr))]
+		return boolTerm(exists), nil
+	}
+
+	args := make([]Term, len(f.args))
+	for i, arg := range f.args {
+		term, err := arg.eval(b)
+		if err != nil {
+			return Term{}, err
+		}
+		args[i] = term
+	}
+
+	switch f.name {
+	case "isiri", "isuri":
+		return boolTerm(args[0].Kind == KindIRI), nil
+	case "isliteral":
+		return boolTerm(args[0].Kind == KindLiteral), nil
+	case "str":
+		if args[0].Kind == KindBlank {
+			return Term{}, errExpression
+		}
+		return Literal(args[0].Value), nil
+	case "lang":
+		if args[0].Kind != KindLiteral {
+			return Term{}, errExpression
+		}
+		return Literal(args[0].Lang), nil
+	}
+
+	// Les fonctions de chaînes n'acceptent que des littéraux
+	for _, arg := range args {
+		if arg.Kind != KindLiteral {
+			return Term{}, errExpression
+		}
+	}
+	switch f.name {
+	case "lcase":
+		return Term{Kind: KindLiteral, Value: strings.ToLower(args[0].Value), Lang: args[0].Lang, Datatype: args[0].Datatype}, nil
+	case "ucase":
+		return Term{Kind: KindLiteral, Value: strings.ToUpper(args[0].Value), Lang: args[0].Lang, Datatype: args[0].Datatype}, nil
+	case "contains":
+		return boolTerm(strings.Contains(args[0].Value, args[1].Value)), nil
+	case "strstarts":
+		return boolTerm(strings.HasPrefix(args[0].Value, args[1].Value)), nil
+	case "strends":
+		return boolTerm(strings.HasSuffix(args[0].Value, args[1].Value)), nil
+	}
+
+	// regex
+	re := f.pattern
+	if re == nil {
+		flags := ""
+		if len(args) == 3 {
+			flags = args[2].Value
+		}
+		var err error
+		if re, err = compileRegex(args[1].Value, flags); err != nil {
+			return Term{}, errExpression
+		}
+	}
+	return boolTerm(re.MatchString(args[0].Value)), nil
+}
+
+// evaluation porte l'état d'exécution d'une requête
+type evaluation struct {
+	dataset *Dataset
+	steps   int
+}
+
+// step décompte une solution intermédiaire et signale le dépassement du budget
+func (e *evaluation) step() error {
+	e.steps++
+	if e.steps > maxSteps {
+		return ErrQueryTooComplex
+	}
+	return nil
+}
+
+// evalGroup étend les solutions avec les éléments d'un groupe, puis applique ses FILTER
+func (e *evaluation) evalGroup(g *group, solutions []binding) ([]binding, error) {
+	var err error
+	for _, element := range g.elements {
+		switch {
+		case element.triple != nil:
+			solutions, err = e.matchTriple(element.triple, solutions)
+		case element.optional:
+			solutions, err = e.leftJoin(element.group, solutions)
+		default:
+			solutions, err = e.evalGroup(element.group, solutions)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(g.filters) == 0 {
+		return solutions, nil
+	}
+
+	var filtered []binding
+	for _, solution := range solutions {
+		keep := true
+		for _, filter := range g.filters {
+			if value, err := evalBoolean(filter, solution); err != nil || !value {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			filtered = append(filtered, solution)
+		}
+	}
+	return filtered, nil
+}
+
+// leftJoin conserve chaque solution telle quelle lorsque le groupe OPTIONAL ne l'étend pas
+func (e *evaluation) leftJoin(g *group, solutions []binding) ([]binding, error) {
+	var joined []binding
+	for _, solution := range solutions {
+		extended, err := e.evalGroup(g, []binding{solution})
+		if err != nil {
+			return nil, err
+		}
+		if len(extended) == 0 {
+			joined = append(joined, solution)
+			continue
+		}
+		joined = append(joined, extended...)
+	}
+	return joined, nil
+}
+
+// matchTriple étend chaque solution avec les triplets correspondant au motif
+func (e *evaluation) matchTriple(pattern *triplePattern, solutions []binding) ([]binding, error) {
+	var matched []binding
+	for _, solution := range solutions {
+		subject, subjectKnown := resolve(pattern.subject, solution)
+		predicate, predicateKnown := resolve(pattern.predicate, solution)
+		object, objectKnown := resolve(pattern.object, solution)
+		for _, i := range e.dataset.candidates(known(subject, subjectKnown), known(predicate, predicateKnown), known(object, objectKnown)) {
+			t := e.dataset.triples[i]
+			current := solution
+			ok := true
+			for _, position := range []struct {
+				node     node
+				term     Term
+				isKnown  bool
+				resolved Term
+			}{
+				{pattern.subject, t.Subject, subjectKnown, subject},
+				{pattern.predicate, t.Predicate, predicateKnown, predicate},
+				{pattern.object, t.Object, objectKnown, object},
+			} {
+				if position.isKnown {
+					if position.resolved != position.term {
+						ok = false
+						break
+					}
+					continue
+				}
+				// Une variable répétée dans le motif doit désigner le même terme
+				if bound, exists := current[position.node.variable]; exists {
+					if bound != position.term {
+						ok = false
+						break
+					}
+					continue
+				}
+				current = current.extend(position.node.variable, position.term)
+			}
+			if !ok {
+				continue
+			}
+			if err := e.step(); err != nil {
+				return nil, err
+			}
+			matched = append(matched, current)
+		}
+	}
+	return matched, nil
+}
+
+// resolve retourne le terme d'une position du motif s'il est constant ou déjà lié
+func resolve(n node, solution binding) (Term, bool) {
+	if !n.isVariable() {
+		return n.term, true
+	}
+	term, exists := solution[n.variable]
+	return term, exists
+}
+
+func known(term Term, isKnown bool) *Term {
+	if !isKnown {
+		return nil
+	}
+	return &term
+}
+
+// orderSolutions trie les solutions selon les critères ORDER BY ; les valeurs non liées passent en premier
+func orderSolutions(solutions []binding, keys []orderKey) {
+	sort.SliceStable(solutions, func(i, j int) bool {
+		for _, key := range keys {
+			a, errA := key.expr.eval(solutions[i])
+			b, errB := key.expr.eval(solutions[j])
+			c := 0
+			switch {
+			case errA != nil && errB != nil:
+			case errA != nil:
+				c = -1
+			case errB != nil:
+				c = 1
+			default:
+				c = compareTerms(a, b)
+			}
+			if c != 0 {
+				return (c < 0) != key.descending
+			}
+		}
+		return false
+	})
+}
+
+// compareTerms ordonne les nœuds anonymes, puis les IRI, puis les littéraux
+func compareTerms(a, b Term) int {
+	rank := map[TermKind]int{KindBlank: 0, KindIRI: 1, KindLiteral: 2}
+	if a.Kind != b.Kind {
+		return rank[a.Kind] - rank[b.Kind]
+	}
+	if a.Kind == KindLiteral {
+		return compareLiterals(a, b)
+	}
+	return strings.Compare(a.Value, b.Value)
+}
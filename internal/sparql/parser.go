@@ -0,0 +1,670 @@
+package sparql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidQuery signale une requête SPARQL mal formée ou non prise en charge
+var ErrInvalidQuery = errors.New("invalid SPARQL query")
+
+// Formes de requête prises en charge
+const (
+	FormSelect    = "SELECT"
+	FormAsk       = "ASK"
+	FormConstruct = "CONSTRUCT"
+)
+
+// node est une position d'un motif de triplet : une variable ou un terme constant
+type node struct {
+	variable string
+	term     Term
+}
+
+func (n node) isVariable() bool {
+	return n.variable != ""
+}
+
+// triplePattern est un motif de triplet du WHERE ou du gabarit CONSTRUCT
+type triplePattern struct {
+	subject, predicate, object node
+}
+
+// group est un motif de groupe { ... } : ses motifs de triplets, groupes imbriqués et OPTIONAL
+// sont évalués dans l'ordre, puis ses FILTER sont appliqués à l'ensemble du groupe
+type group struct {
+	elements []groupElement
+	filters  []expression
+}
+
+// groupElement est un triplet, un groupe imbriqué ou un groupe OPTIONAL
+type groupElement struct {
+	triple   *triplePattern
+	group    *group
+	optional bool
+}
+
+// orderKey est un critère de tri ORDER BY
+type orderKey struct {
+	expr       expression
+	descending bool
+}
+
+// Query est une requête SPARQL analysée
+type Query struct {
+	Form     string
+	Distinct bool
+	// vars est la projection du SELECT, vide pour SELECT *
+	vars     []string
+	template []triplePattern
+	where    *group
+	orderBy  []orderKey
+	limit    int // -1 sans clause LIMIT
+	offset   int
+}
+
+// sparqlToken est un lexème d'une requête SPARQL
+type sparqlToken struct {
+	kind  string // iri, pname, var, string, number, word, punct, eof
+	value string
+	// lang est l'étiquette de langue d'un littéral "..."@fr
+	lang string
+}
+
+// lexer découpe une requête SPARQL en lexèmes
+func lex(text string) ([]sparqlToken, error) {
+	var tokens []sparqlToken
+	runes := []rune(text)
+	isName := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'
+	}
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '<' && isIRIRef(runes[i+1:]):
+			j := i + 1
+			for runes[j] != '>' {
+				j++
+			}
+			tokens = append(tokens, sparqlToken{kind: "iri", value: string(runes[i+1 : j])})
+			i = j + 1
+		case r == '?' || r == '$':
+			j := i + 1
+			for j < len(runes) && isName(runes[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("%w: empty variable name", ErrInvalidQuery)
+			}
+			tokens = append(tokens, sparqlToken{kind: "var", value: string(runes[i+1 : j])})
+			i = j
+		case r == '"' || r == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+					switch runes[j] {
+					case 'n':
+						b.WriteRune('\n')
+					case 't':
+						b.WriteRune('\t')
+					default:
+						b.WriteRune(runes[j])
+					}
+					continue
+				}
+				b.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidQuery)
+			}
+			tok := sparqlToken{kind: "string", value: b.String()}
+			i = j + 1
+			if i < len(runes) && runes[i] == '@' {
+				j = i + 1
+				for j < len(runes) && isName(runes[j]) {
+					j++
+				}
+				tok.lang = strings.ToLower(string(runes[i+1 : j]))
+				i = j
+			}
+			tokens = append(tokens, tok)
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || (runes[j] == '.' && j+1 < len(runes) && unicode.IsDigit(runes[j+1]))) {
+				j++
+			}
+			tokens = append(tokens, sparqlToken{kind: "number", value: string(runes[i:j])})
+			i = j
+		case isName(r) || r == ':':
+			j := i
+			for j < len(runes) && isName(runes[j]) {
+				j++
+			}
+			if j < len(runes) && runes[j] == ':' {
+				// Nom préfixé : la partie locale peut contenir des points, sauf en dernière position
+				k := j + 1
+				for k < len(runes) && (isName(runes[k]) || runes[k] == '%' || (runes[k] == '.' && k+1 < len(runes) && isName(runes[k+1]))) {
+					k++
+				}
+				tokens = append(tokens, sparqlToken{kind: "pname", value: string(runes[i:k])})
+				i = k
+				continue
+			}
+			tokens = append(tokens, sparqlToken{kind: "word", value: string(runes[i:j])})
+			i = j
+		default:
+			op := string(r)
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "&&", "||", "!=", "<=", ">=", "^^":
+					op = two
+				}
+			}
+			if !strings.Contains("{}().;,*=<>!&|^", string(r)) || op == "&" || op == "|" || op == "^" {
+				return nil, fmt.Errorf("%w: unexpected character %q", ErrInvalidQuery, r)
+			}
+			tokens = append(tokens, sparqlToken{kind: "punct", value: op})
+			i += len([]rune(op))
+		}
+	}
+	return append(tokens, sparqlToken{kind: "eof"}), nil
+}
+
+// isIRIRef indique si le texte qui suit un '<' est une IRI et non un opérateur de comparaison
+func isIRIRef(runes []rune) bool {
+	for _, r := range runes {
+		switch {
+		case r == '>':
+			return true
+		case unicode.IsSpace(r) || r == '<' || r == '"' || r == '{' || r == '}':
+			return false
+		}
+	}
+	return false
+}
+
+// queryParser analyse une requête SPARQL par descente récursive
+type queryParser struct {
+	tokens   []sparqlToken
+	pos      int
+	prefixes map[string]string
+	// seen liste les variables du WHERE dans leur ordre d'apparition, pour SELECT *
+	seen []string
+}
+
+// ParseQuery analyse une requête SPARQL
+func ParseQuery(text string) (*Query, error) {
+	tokens, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens, prefixes: make(map[string]string)}
+	for prefix, iri := range defaultPrefixes {
+		p.prefixes[prefix] = iri
+	}
+	q, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	return q, nil
+}
+
+func (p *queryParser) peek() sparqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() sparqlToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != "eof" {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword consomme un mot-clé, sans tenir compte de la casse
+func (p *queryParser) keyword(word string) bool {
+	if tok := p.peek(); tok.kind == "word" && strings.EqualFold(tok.value, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) accept(punct string) bool {
+	if tok := p.peek(); tok.kind == "punct" && tok.value == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(punct string) error {
+	if !p.accept(punct) {
+		return fmt.Errorf("expected %q, got %q", punct, p.peek().value)
+	}
+	return nil
+}
+
+func (p *queryParser) parse() (*Query, error) {
+	q := &Query{limit: -1}
+	for {
+		if p.keyword("PREFIX") {
+			tok := p.next()
+			if tok.kind != "pname" || !strings.HasSuffix(tok.value, ":") {
+				return nil, fmt.Errorf("expected prefix name, got %q", tok.value)
+			}
+			iri := p.next()
+			if iri.kind != "iri" {
+				return nil, fmt.Errorf("expected IRI for prefix %s", tok.value)
+			}
+			p.prefixes[strings.TrimSuffix(tok.value, ":")] = iri.value
+			continue
+		}
+		break
+	}
+
+	switch {
+	case p.keyword("SELECT"):
+		q.Form = FormSelect
+		q.Distinct = p.keyword("DISTINCT")
+		if !q.Distinct {
+			p.keyword("REDUCED")
+		}
+		if !p.accept("*") {
+			for p.peek().kind == "var" {
+				q.vars = append(q.vars, p.next().value)
+			}
+			if len(q.vars) == 0 {
+				return nil, fmt.Errorf("SELECT expects variables or *")
+			}
+		}
+	case p.keyword("ASK"):
+		q.Form = FormAsk
+	case p.keyword("CONSTRUCT"):
+		q.Form = FormConstruct
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		for !p.accept("}") {
+			if p.accept(".") {
+				continue
+			}
+			triples, err := p.parseTriples()
+			if err != nil {
+				return nil, err
+			}
+			q.template = append(q.template, triples...)
+		}
+	default:
+		return nil, fmt.Errorf("expected SELECT, ASK or CONSTRUCT, got %q", p.peek().value)
+	}
+
+	p.keyword("WHERE")
+	where, err := p.parseGroup()
+	if err != nil {
+		return nil, err
+	}
+	q.where = where
+	if q.Form == FormSelect && len(q.vars) == 0 {
+		q.vars = p.seen
+	}
+
+	if p.keyword("ORDER") {
+		if !p.keyword("BY") {
+			return nil, fmt.Errorf("expected BY after ORDER")
+		}
+		for {
+			key, ok, err := p.parseOrderKey()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+			q.orderBy = append(q.orderBy, key)
+		}
+		if len(q.orderBy) == 0 {
+			return nil, fmt.Errorf("ORDER BY expects at least one condition")
+		}
+	}
+	for {
+		var target *int
+		switch {
+		case p.keyword("LIMIT"):
+			target = &q.limit
+		case p.keyword("OFFSET"):
+			target = &q.offset
+		}
+		if target == nil {
+			break
+		}
+		tok := p.next()
+		n, err := strconv.Atoi(tok.value)
+		if tok.kind != "number" || err != nil || n < 0 {
+			return nil, fmt.Errorf("expected a non-negative integer, got %q", tok.value)
+		}
+		*target = n
+	}
+
+	if tok := p.peek(); tok.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q", tok.value)
+	}
+	return q, nil
+}
+
+// parseOrderKey analyse un critère ORDER BY : ?x, ASC(expr), DESC(expr) ou (expr)
+func (p *queryParser) parseOrderKey() (orderKey, bool, error) {
+	tok := p.peek()
+	switch {
+	case tok.kind == "var":
+		p.pos++
+		return orderKey{expr: variableExpr(tok.value)}, true, nil
+	case p.keyword("ASC"), p.keyword("DESC"):
+		descending := strings.EqualFold(tok.value, "DESC")
+		expr, err := p.parseBracketted()
+		return orderKey{expr: expr, descending: descending}, true, err
+	case tok.kind == "punct" && tok.value == "(":
+		expr, err := p.parseBracketted()
+		return orderKey{expr: expr}, true, err
+	}
+	return orderKey{}, false, nil
+}
+
+// parseGroup analyse un motif de groupe { ... }
+func (p *queryParser) parseGroup() (*group, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	g := &group{}
+	for !p.accept("}") {
+		tok := p.peek()
+		switch {
+		case tok.kind == "eof":
+			return nil, fmt.Errorf("unterminated group")
+		case p.accept("."):
+		case p.keyword("OPTIONAL"):
+			sub, err := p.parseGroup()
+			if err != nil {
+				return nil, err
+			}
+			g.elements = append(g.elements, groupElement{group: sub, optional: true})
+		case p.keyword("FILTER"):
+			expr, err := p.parseConstraint()
+			if err != nil {
+				return nil, err
+			}
+			g.filters = append(g.filters, expr)
+		case tok.kind == "punct" && tok.value == "{":
+			sub, err := p.parseGroup()
+			if err != nil {
+				return nil, err
+			}
+			if p.keyword("UNION") {
+				return nil, fmt.Errorf("UNION is not supported")
+			}
+			g.elements = append(g.elements, groupElement{group: sub})
+		case tok.kind == "word" && isUnsupportedKeyword(tok.value):
+			return nil, fmt.Errorf("%s is not supported", strings.ToUpper(tok.value))
+		default:
+			triples, err := p.parseTriples()
+			if err != nil {
+				return nil, err
+			}
+			for i := range triples {
+				g.elements = append(g.elements, groupElement{triple: &triples[i]})
+			}
+		}
+	}
+	return g, nil
+}
+
+func isUnsupportedKeyword(word string) bool {
+	switch strings.ToUpper(word) {
+	case "UNION", "MINUS", "GRAPH", "SERVICE", "BIND", "VALUES":
+		return true
+	}
+	return false
+}
+
+// parseTriples analyse un sujet et sa liste de propriétés, avec les abréviations ';' et ','
+func (p *queryParser) parseTriples() ([]triplePattern, error) {
+	subject, err := p.parseNode(false)
+	if err != nil {
+		return nil, err
+	}
+	var triples []triplePattern
+	for {
+		predicate, err := p.parseNode(true)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			object, err := p.parseNode(false)
+			if err != nil {
+				return nil, err
+			}
+			triples = append(triples, triplePattern{subject, predicate, object})
+			if !p.accept(",") {
+				break
+			}
+		}
+		if !p.accept(";") {
+			break
+		}
+		// Un ';' final est autorisé avant '.' ou '}'
+		if tok := p.peek(); tok.kind == "punct" && (tok.value == "." || tok.value == "}") {
+			break
+		}
+	}
+	return triples, nil
+}
+
+// parseNode analyse une variable ou un terme ; en position de prédicat, 'a' désigne rdf:type
+func (p *queryParser) parseNode(predicate bool) (node, error) {
+	tok := p.peek()
+	if tok.kind == "var" {
+		p.pos++
+		p.see(tok.value)
+		return node{variable: tok.value}, nil
+	}
+	if predicate && tok.kind == "word" && tok.value == "a" {
+		p.pos++
+		return node{term: rdfType}, nil
+	}
+	term, err := p.parseTerm()
+	if err != nil {
+		return node{}, err
+	}
+	if predicate && term.Kind != KindIRI {
+		return node{}, fmt.Errorf("predicate must be an IRI or a variable")
+	}
+	return node{term: term}, nil
+}
+
+// see enregistre une variable du WHERE pour la projection SELECT *
+func (p *queryParser) see(variable string) {
+	for _, v := range p.seen {
+		if v == variable {
+			return
+		}
+	}
+	p.seen = append(p.seen, variable)
+}
+
+// parseTerm analyse une IRI, un nom préfixé, un littéral, un nombre ou un booléen
+func (p *queryParser) parseTerm() (Term, error) {
+	tok := p.next()
+	switch tok.kind {
+	case "iri":
+		return IRI(tok.value), nil
+	case "pname":
+		return p.expand(tok.value)
+	case "number":
+		if strings.Contains(tok.value, ".") {
+			return TypedLiteral(tok.value, xsdIRI+"decimal"), nil
+		}
+		return TypedLiteral(tok.value, xsdIRI+"integer"), nil
+	case "string":
+		term := Literal(tok.value)
+		term.Lang = tok.lang
+		if p.accept("^^") {
+			datatype, err := p.parseTerm()
+			if err != nil || datatype.Kind != KindIRI {
+				return Term{}, fmt.Errorf("expected datatype IRI after ^^")
+			}
+			term.Datatype = datatype.Value
+		}
+		return term, nil
+	case "word":
+		if tok.value == "true" || tok.value == "false" {
+			return TypedLiteral(tok.value, xsdIRI+"boolean"), nil
+		}
+	}
+	return Term{}, fmt.Errorf("unexpected %q", tok.value)
+}
+
+// expand résout un nom préfixé en IRI
+func (p *queryParser) expand(pname string) (Term, error) {
+	i := strings.Index(pname, ":")
+	iri, exists := p.prefixes[pname[:i]]
+	if !exists {
+		return Term{}, fmt.Errorf("undeclared prefix %q", pname[:i])
+	}
+	return IRI(iri + pname[i+1:]), nil
+}
+
+// parseConstraint analyse la contrainte d'un FILTER : expression entre parenthèses ou appel de fonction
+func (p *queryParser) parseConstraint() (expression, error) {
+	if tok := p.peek(); tok.kind == "word" {
+		return p.parsePrimary()
+	}
+	return p.parseBracketted()
+}
+
+func (p *queryParser) parseBracketted() (expression, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	return expr, p.expect(")")
+}
+
+func (p *queryParser) parseOr() (expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (expression, error) {
+	left, err := p.parseRelational()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseRelational()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseRelational() (expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"=", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return compareExpr{op, left, right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (expression, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (expression, error) {
+	tok := p.peek()
+	switch {
+	case tok.kind == "punct" && tok.value == "(":
+		return p.parseBracketted()
+	case tok.kind == "var":
+		p.pos++
+		return variableExpr(tok.value), nil
+	case tok.kind == "word" && tok.value != "true" && tok.value != "false":
+		p.pos++
+		name := strings.ToLower(tok.value)
+		arity, exists := functionArity[name]
+		if !exists {
+			return nil, fmt.Errorf("unsupported function %q", tok.value)
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var args []expression
+		for !p.accept(")") {
+			if len(args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		if len(args) < arity[0] || len(args) > arity[1] {
+			return nil, fmt.Errorf("wrong number of arguments for %s", tok.value)
+		}
+		if name == "bound" {
+			if _, ok := args[0].(variableExpr); !ok {
+				return nil, fmt.Errorf("BOUND expects a variable")
+			}
+		}
+		return newFunction(name, args)
+	}
+	term, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	return constantExpr{term}, nil
+}
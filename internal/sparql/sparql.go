@@ -0,0 +1,210 @@
+// Package sparql expose les ontologies chargées sous forme de triplets RDF interrogeables en SPARQL.
+// Il prend en charge les requêtes SELECT, ASK et CONSTRUCT composées de motifs de triplets,
+// de groupes OPTIONAL et de FILTER, avec ORDER BY, LIMIT et OFFSET.
+package sparql
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/chrlesur/ontology-server/internal/graph"
+	"github.com/chrlesur/ontology-server/internal/storage"
+)
+
+// Service répond aux requêtes SPARQL sur le jeu de triplets des ontologies stockées
+type Service struct {
+	storage *storage.MemoryStorage
+	graph   *graph.Graph
+
+	mutex   sync.Mutex
+	dataset *Dataset
+}
+
+// New crée le service et invalide son jeu de triplets à chaque modification d'une ontologie.
+// Le graphe doit avoir été créé avant le service, pour être rafraîchi avant l'invalidation.
+func New(ms *storage.MemoryStorage, g *graph.Graph) *Service {
+	s := &Service{storage: ms, graph: g}
+	ms.OnChange(func(string) {
+		s.mutex.Lock()
+		s.dataset = nil
+		s.mutex.Unlock()
+	})
+	return s
+}
+
+// Dataset retourne le jeu de triplets des ontologies, construit à la première requête qui suit une modification
+func (s *Service) Dataset() *Dataset {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.dataset == nil {
+		s.dataset = buildDataset(s.storage.ListOntologies(), s.graph)
+	}
+	return s.dataset
+}
+
+// Result est le résultat d'une requête SPARQL
+type Result struct {
+	Form string
+	// Vars et Bindings sont les colonnes et les solutions d'un SELECT
+	Vars     []string
+	Bindings []map[string]Term
+	// Boolean est la réponse d'un ASK
+	Boolean bool
+	// Triples est le graphe produit par un CONSTRUCT
+	Triples []Triple
+}
+
+// Query analyse et évalue une requête SPARQL
+func (s *Service) Query(text string) (*Result, error) {
+	q, err := ParseQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	return q.Eval(s.Dataset())
+}
+
+// Eval évalue la requête sur un jeu de triplets
+func (q *Query) Eval(d *Dataset) (*Result, error) {
+	e := &evaluation{dataset: d}
+	solutions, err := e.evalGroup(q.where, []binding{{}})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Form: q.Form}
+	if q.Form == FormAsk {
+		result.Boolean = len(solutions) > 0
+		return result, nil
+	}
+
+	if len(q.orderBy) > 0 {
+		orderSolutions(solutions, q.orderBy)
+	}
+	if q.Form == FormSelect {
+		solutions = project(solutions, q.vars, q.Distinct)
+	}
+	solutions = slice(solutions, q.offset, q.limit)
+
+	if q.Form == FormConstruct {
+		result.Triples = instantiate(q.template, solutions)
+		return result, nil
+	}
+	result.Vars = q.vars
+	result.Bindings = make([]map[string]Term, len(solutions))
+	for i, solution := range solutions {
+		result.Bindings[i] = solution
+	}
+	return result, nil
+}
+
+// project restreint les solutions aux variables sélectionnées, en éliminant les doublons avec DISTINCT
+func project(solutions []binding, vars []string, distinct bool) []binding {
+	seen := make(map[string]bool)
+	var projected []binding
+	for _, solution := range solutions {
+		row := make(binding, len(vars))
+		var key strings.Builder
+		for _, v := range vars {
+			if term, exists := solution[v]; exists {
+				row[v] = term
+				key.WriteString(term.String())
+			}
+			key.WriteByte(0)
+		}
+		if distinct {
+			if seen[key.String()] {
+				continue
+			}
+			seen[key.String()] = true
+		}
+		projected = append(projected, row)
+	}
+	return projected
+}
+
+// slice applique OFFSET et LIMIT ; une limite négative signale l'absence de LIMIT
+func slice(solutions []binding, offset, limit int) []binding {
+	if offset >= len(solutions) {
+		return nil
+	}
+	solutions = solutions[offset:]
+	if limit >= 0 && limit < len(solutions) {
+		solutions = solutions[:limit]
+	}
+	return solutions
+}
+
+// instantiate produit les triplets du gabarit CONSTRUCT pour chaque solution ; les triplets dont une
+// variable n'est pas liée, ou dont le sujet est un littéral, sont ignorés
+func instantiate(template []triplePattern, solutions []binding) []Triple {
+	seen := make(map[string]bool)
+	var triples []Triple
+	for _, solution := range solutions {
+		for _, pattern := range template {
+			subject, okS := resolve(pattern.subject, solution)
+			predicate, okP := resolve(pattern.predicate, solution)
+			object, okO := resolve(pattern.object, solution)
+			if !okS || !okP || !okO || subject.Kind == KindLiteral || predicate.Kind != KindIRI {
+				continue
+			}
+			t := Triple{subject, predicate, object}
+			if key := t.String(); !seen[key] {
+				seen[key] = true
+				triples = append(triples, t)
+			}
+		}
+	}
+	return triples
+}
+
+// JSON retourne le résultat d'un SELECT ou d'un ASK au format SPARQL 1.1 Query Results JSON
+func (r *Result) JSON() map[string]interface{} {
+	if r.Form == FormAsk {
+		return map[string]interface{}{
+			"head":    map[string]interface{}{},
+			"boolean": r.Boolean,
+		}
+	}
+	vars := r.Vars
+	if vars == nil {
+		vars = []string{}
+	}
+	bindings := make([]map[string]interface{}, len(r.Bindings))
+	for i, solution := range r.Bindings {
+		row := make(map[string]interface{}, len(solution))
+		for v, term := range solution {
+			row[v] = termJSON(term)
+		}
+		bindings[i] = row
+	}
+	return map[string]interface{}{
+		"head":    map[string]interface{}{"vars": vars},
+		"results": map[string]interface{}{"bindings": bindings},
+	}
+}
+
+func termJSON(term Term) map[string]string {
+	switch term.Kind {
+	case KindIRI:
+		return map[string]string{"type": "uri", "value": term.Value}
+	case KindBlank:
+		return map[string]string{"type": "bnode", "value": term.Value}
+	}
+	value := map[string]string{"type": "literal", "value": term.Value}
+	if term.Lang != "" {
+		value["xml:lang"] = term.Lang
+	} else if term.Datatype != "" {
+		value["datatype"] = term.Datatype
+	}
+	return value
+}
+
+// NTriples retourne le graphe d'un CONSTRUCT au format N-Triples
+func (r *Result) NTriples() string {
+	var b strings.Builder
+	for _, t := range r.Triples {
+		b.WriteString(t.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
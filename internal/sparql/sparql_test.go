@@ -0,0 +1,207 @@
+package sparql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrlesur/ontology-server/internal/graph"
+	"github.com/chrlesur/ontology-server/internal/models"
+	"github.com/chrlesur/ontology-server/internal/storage"
+)
+
+func newTestService() (*storage.MemoryStorage, *Service) {
+	ms := storage.NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID:         "onto1",
+		Name:       "Sport",
+		ImportedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		Elements: []*models.OntologyElement{
+			{Name: "Club_Sportif", Type: "Organisation", Description: "Association sportive",
+				Contexts: []models.JSONContext{{FileID: "f1"}}},
+			{Name: "Entraineur", Type: "Rôle/Personne", AltLabels: []string{"Coach"}},
+			{Name: "Joueur", Type: "Rôle"},
+		},
+		Relations: []*models.Relation{
+			{Source: "Club_Sportif", Type: "emploie", Target: "Entraineur", Description: "contrat"},
+			{Source: "Entraineur", Type: "entraine", Target: "Joueur"},
+		},
+		RelationTypes: []*models.RelationType{{Name: "emploie", InverseOf: "travaille_pour"}},
+		Source: &models.SourceMetadata{Files: map[string]models.FileInfo{
+			"f1": {ID: "f1", SourceFile: "docs/club.txt", SHA256Hash: "abc"},
+		}},
+	})
+	g := graph.New(ms)
+	return ms, New(ms, g)
+}
+
+func labels(result *Result, variable string) []string {
+	var values []string
+	for _, solution := range result.Bindings {
+		values = append(values, solution[variable].Value)
+	}
+	return values
+}
+
+func TestSelect(t *testing.T) {
+	_, s := newTestService()
+
+	result, err := s.Query(`
+		SELECT ?name ?type WHERE {
+			?e a os:Element ; rdfs:label ?name ; os:elementType ?type .
+			FILTER(?type = "Rôle")
+		} ORDER BY ?name`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if got := strings.Join(labels(result, "name"), ","); got != "Entraineur,Joueur" {
+		t.Errorf("Expected Entraineur,Joueur, got %s", got)
+	}
+
+	// Les relations déduites sont exposées comme les relations déclarées
+	result, err = s.Query(`SELECT ?who WHERE { ?e rel:travaille_pour ?c . ?c rdfs:label "Club_Sportif" . ?e rdfs:label ?who }`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if got := labels(result, "who"); len(got) != 1 || got[0] != "Entraineur" {
+		t.Errorf("Expected the inferred travaille_pour relation, got %v", got)
+	}
+
+	// OPTIONAL conserve les éléments sans description, LIMIT et OFFSET découpent les solutions
+	result, err = s.Query(`SELECT * WHERE { ?e rdfs:label ?name OPTIONAL { ?e rdfs:comment ?d } FILTER(?e != <x>) } ORDER BY DESC(?name) LIMIT 2 OFFSET 1`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if strings.Join(result.Vars, ",") != "e,name,d" {
+		t.Errorf("Expected variables e,name,d, got %v", result.Vars)
+	}
+	if got := strings.Join(labels(result, "name"), ","); got != "Joueur,Entraineur" {
+		t.Errorf("Expected Joueur,Entraineur, got %s", got)
+	}
+	if _, exists := result.Bindings[1]["d"]; exists {
+		t.Errorf("Expected no description for Entraineur, got %v", result.Bindings[1])
+	}
+
+	result, err = s.Query(`SELECT DISTINCT ?p WHERE { ?s ?p ?o FILTER(strstarts(str(?p), str(rel:))) }`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Bindings) != 3 {
+		t.Errorf("Expected 3 relation predicates, got %v", labels(result, "p"))
+	}
+}
+
+func TestProvenanceAndReification(t *testing.T) {
+	_, s := newTestService()
+
+	result, err := s.Query(`
+		SELECT ?path ?comment ?inferred WHERE {
+			?e rdfs:label ?l ; os:mentionedIn ?f .
+			?f os:path ?path .
+			?st rdf:subject ?e ; rdf:predicate rel:emploie ; os:inferred ?inferred .
+			OPTIONAL { ?st rdfs:comment ?comment }
+			FILTER regex(?l, "^club", "i")
+		}`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Bindings) != 1 {
+		t.Fatalf("Expected 1 solution, got %d", len(result.Bindings))
+	}
+	row := result.Bindings[0]
+	if row["path"].Value != "docs/club.txt" || row["comment"].Value != "contrat" || row["inferred"].Value != "false" {
+		t.Errorf("Unexpected provenance %v", row)
+	}
+}
+
+func TestAskAndConstruct(t *testing.T) {
+	_, s := newTestService()
+
+	result, err := s.Query(`ASK { ?e skos:altLabel "Coach" }`)
+	if err != nil || !result.Boolean {
+		t.Errorf("Expected ASK to be true, got %v (%v)", result, err)
+	}
+	result, err = s.Query(`ASK WHERE { ?e skos:altLabel "Arbitre" }`)
+	if err != nil || result.Boolean {
+		t.Errorf("Expected ASK to be false, got %v (%v)", result, err)
+	}
+	if json := result.JSON(); json["boolean"] != false {
+		t.Errorf("Expected a boolean JSON result, got %v", json)
+	}
+
+	result, err = s.Query(`
+		PREFIX ex: <http://example.org/>
+		CONSTRUCT { ?b ex:employeur ?a } WHERE { ?a rel:emploie ?b }`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	expected := "<http://ontology-server/ontology/onto1/element/Entraineur> <http://example.org/employeur> <http://ontology-server/ontology/onto1/element/Club%20Sportif> .\n"
+	if got := result.NTriples(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestUndeclaredEndpointsAndEscaping(t *testing.T) {
+	ms := storage.NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID:       "onto1",
+		Elements: []*models.OntologyElement{{Name: "Club", Description: "ligne 1\nligne 2 \x01 \"cité\""}},
+		Relations: []*models.Relation{
+			{Source: "Federation", Type: "agree", Target: "Club"},
+			{Source: "Club", Type: "affilie_a", Target: "Ligue"},
+			{Source: "Ligue", Type: "depend_de", Target: "Federation"},
+		},
+	})
+	s := New(ms, graph.New(ms))
+
+	// Les extrémités non déclarées, en source comme en cible, portent un libellé et leur ontologie
+	result, err := s.Query(`SELECT ?name WHERE { ?e rdfs:label ?name ; os:ontology ?o } ORDER BY ?name`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if got := strings.Join(labels(result, "name"), ","); got != "Club,Federation,Ligue" {
+		t.Errorf("Expected Club,Federation,Ligue, got %s", got)
+	}
+
+	result, err = s.Query(`CONSTRUCT { ?e rdfs:comment ?d } WHERE { ?e rdfs:comment ?d }`)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if got := result.NTriples(); !strings.HasSuffix(got, ` "ligne 1\nligne 2 \u0001 \"cité\"" .`+"\n") {
+		t.Errorf("Expected an N-Triples escaped literal, got %q", got)
+	}
+}
+
+func TestDatasetFollowsStorage(t *testing.T) {
+	ms, s := newTestService()
+	before := s.Dataset().Len()
+
+	ms.SetElementAltLabels("onto1", "Joueur", []string{"Athlète"})
+	if after := s.Dataset().Len(); after != before+1 {
+		t.Errorf("Expected one more triple after the update, got %d then %d", before, after)
+	}
+	ms.DeleteOntology("onto1")
+	if n := s.Dataset().Len(); n != 0 {
+		t.Errorf("Expected an empty dataset after deletion, got %d triples", n)
+	}
+}
+
+func TestInvalidQueries(t *testing.T) {
+	_, s := newTestService()
+
+	for _, query := range []string{
+		"",
+		"SELECT WHERE { ?s ?p ?o }",
+		"SELECT ?s WHERE { ?s ?p ?o",
+		"SELECT ?s WHERE { ?s unknown:p ?o }",
+		"SELECT ?s WHERE { { ?s ?p ?o } UNION { ?o ?p ?s } }",
+		"SELECT ?s WHERE { ?s ?p ?o FILTER(nope(?s)) }",
+		"SELECT ?s WHERE { ?s ?p ?o } LIMIT -1",
+		"DESCRIBE <x>",
+	} {
+		if _, err := s.Query(query); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Expected ErrInvalidQuery for %q, got %v", query, err)
+		}
+	}
+}
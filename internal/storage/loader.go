@@ -14,9 +14,13 @@ import (
 	"github.com/chrlesur/ontology-server/internal/parser"
 )
 
+// LoadHook is called after an ontology has been loaded from files and stored
+type LoadHook func(ontology *models.Ontology)
+
 type OntologyLoader struct {
 	storage *MemoryStorage
 	logger  *logger.Logger
+	hooks   []LoadHook
 }
 
 func NewOntologyLoader(storage *MemoryStorage, logger *logger.Logger) *OntologyLoader {
@@ -26,6 +30,11 @@ func NewOntologyLoader(storage *MemoryStorage, logger *logger.Logger) *OntologyL
 	}
 }
 
+// AddHook registers a hook run after each successful load
+func (l *OntologyLoader) AddHook(hook LoadHook) {
+	l.hooks = append(l.hooks, hook)
+}
+
 // LoadFiles charge une ontologie avec ses métadonnées et contextes
 func (l *OntologyLoader) LoadFiles(ontologyFile, contextFile, metadataFile string) error {
 	l.logger.Info(fmt.Sprintf("Starting to load files: ontology=%s, context=%s, metadata=%s", ontologyFile, contextFile, metadataFile))
@@ -75,6 +84,15 @@ func (l *OntologyLoader) LoadFiles(ontologyFile, contextFile, metadataFile strin
 	}
 	l.logger.Info(fmt.Sprintf("Ontology added to storage successfully with ID: %s", ontology.ID))
 
+	// Les extrémités des relations ont été rattachées aux éléments lors de l'ajout
+	for _, endpoint := range ontology.UnresolvedEndpoints {
+		l.logger.Info(fmt.Sprintf("Unresolved %s '%s' in relation %d (candidates: %v)", endpoint.Endpoint, endpoint.Name, endpoint.Relation, endpoint.Candidates))
+	}
+
+	for _, hook := range l.hooks {
+		hook(ontology)
+	}
+
 	return nil
 }
 
@@ -23,11 +23,19 @@ func init() {
 	}
 }
 
+// ChangeListener is called after an ontology has been added, updated or deleted
+type ChangeListener func(ontologyID string)
+
 // MemoryStorage represents an in-memory storage for ontologies
 type MemoryStorage struct {
 	ontologies map[string]*models.Ontology
 	mutex      sync.RWMutex
 	loader     *OntologyLoader
+
+	listenersMutex sync.RWMutex
+	listeners      []ChangeListener
+
+	types typeHierarchies
 }
 
 // NewMemoryStorage initializes and returns a new MemoryStorage
@@ -39,17 +47,42 @@ func NewMemoryStorage() *MemoryStorage {
 	return ms
 }
 
+// OnChange registers a listener notified of every ontology change.
+// Listeners are called once the storage lock has been released.
+func (ms *MemoryStorage) OnChange(listener ChangeListener) {
+	ms.listenersMutex.Lock()
+	defer ms.listenersMutex.Unlock()
+	ms.listeners = append(ms.listeners, listener)
+}
+
+// notifyChange calls the registered listeners for an ontology
+func (ms *MemoryStorage) notifyChange(ontologyID string) {
+	ms.types.forget(ontologyID)
+
+	ms.listenersMutex.RLock()
+	listeners := append([]ChangeListener(nil), ms.listeners...)
+	ms.listenersMutex.RUnlock()
+
+	for _, listener := range listeners {
+		listener(ontologyID)
+	}
+}
+
 // AddOntology adds a new ontology to the storage
 func (ms *MemoryStorage) AddOntology(ontology *models.Ontology) error {
-	ms.mutex.Lock()
-	defer ms.mutex.Unlock()
+	resolveOntologyEndpoints(ontology)
 
+	ms.mutex.Lock()
 	if _, exists := ms.ontologies[ontology.ID]; exists {
+		ms.mutex.Unlock()
 		return fmt.Errorf("ontology with ID %s already exists", ontology.ID)
 	}
 
 	ms.ontologies[ontology.ID] = ontology
+	ms.mutex.Unlock()
+
 	log.Info(fmt.Sprintf("Added ontology with ID: %s", ontology.ID))
+	ms.notifyChange(ontology.ID)
 	return nil
 }
 
@@ -68,32 +101,111 @@ func (ms *MemoryStorage) GetOntology(id string) (*models.Ontology, error) {
 
 // UpdateOntology updates an existing ontology
 func (ms *MemoryStorage) UpdateOntology(ontology *models.Ontology) error {
-	ms.mutex.Lock()
-	defer ms.mutex.Unlock()
+	resolveOntologyEndpoints(ontology)
 
+	ms.mutex.Lock()
 	if _, exists := ms.ontologies[ontology.ID]; !exists {
+		ms.mutex.Unlock()
 		return fmt.Errorf("ontology with ID %s not found", ontology.ID)
 	}
 
 	ms.ontologies[ontology.ID] = ontology
+	ms.mutex.Unlock()
+
 	log.Info(fmt.Sprintf("Updated ontology with ID: %s", ontology.ID))
+	ms.notifyChange(ontology.ID)
 	return nil
 }
 
 // DeleteOntology removes an ontology by its ID
 func (ms *MemoryStorage) DeleteOntology(id string) error {
 	ms.mutex.Lock()
-	defer ms.mutex.Unlock()
-
 	if _, exists := ms.ontologies[id]; !exists {
+		ms.mutex.Unlock()
 		return fmt.Errorf("ontology with ID %s not found", id)
 	}
 
 	delete(ms.ontologies, id)
+	ms.mutex.Unlock()
+
 	log.Info(fmt.Sprintf("Deleted ontology with ID: %s", id))
+	ms.notifyChange(id)
+	return nil
+}
+
+// SetElementAltLabels replaces the alternate labels of an element.
+// The ontology is replaced by a shallow copy so that indexes built on the
+// previous version notice the change and rebuild.
+func (ms *MemoryStorage) SetElementAltLabels(ontologyID, elementName string, labels []string) (*models.OntologyElement, error) {
+	var updatedElement *models.OntologyElement
+	err := ms.replaceOntology(ontologyID, func(ontology *models.Ontology) error {
+		for i, element := range ontology.Elements {
+			if element.Name != elementName {
+				continue
+			}
+			copied := *element
+			copied.AltLabels = labels
+			ontology.Elements = append([]*models.OntologyElement(nil), ontology.Elements...)
+			ontology.Elements[i] = &copied
+			updatedElement = &copied
+			return nil
+		}
+		return fmt.Errorf("element %s not found in ontology %s", elementName, ontologyID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info(fmt.Sprintf("Updated alternate labels of element %s in ontology %s: %v", elementName, ontologyID, labels))
+	return updatedElement, nil
+}
+
+// replaceOntology applies mutate to a shallow copy of an ontology and stores the copy.
+// Replacing the ontology rather than modifying it lets indexes built on the
+// previous version notice the change and rebuild. If mutate fails, the stored
+// ontology is left unchanged.
+func (ms *MemoryStorage) replaceOntology(id string, mutate func(*models.Ontology) error) error {
+	ms.mutex.Lock()
+	ontology, exists := ms.ontologies[id]
+	if !exists {
+		ms.mutex.Unlock()
+		return fmt.Errorf("ontology with ID %s not found", id)
+	}
+	updated := *ontology
+	if err := mutate(&updated); err != nil {
+		ms.mutex.Unlock()
+		return err
+	}
+	ms.ontologies[id] = &updated
+	ms.mutex.Unlock()
+
+	ms.notifyChange(id)
 	return nil
 }
 
+// SetRelationTypes replaces the relation type declarations of an ontology
+func (ms *MemoryStorage) SetRelationTypes(ontologyID string, types []*models.RelationType) error {
+	err := ms.replaceOntology(ontologyID, func(ontology *models.Ontology) error {
+		ontology.RelationTypes = types
+		return nil
+	})
+	if err == nil {
+		log.Info(fmt.Sprintf("Updated %d relation type declarations of ontology %s", len(types), ontologyID))
+	}
+	return err
+}
+
+// SetRules replaces the inference rules of an ontology
+func (ms *MemoryStorage) SetRules(ontologyID string, rules []*models.Rule) error {
+	err := ms.replaceOntology(ontologyID, func(ontology *models.Ontology) error {
+		ontology.Rules = rules
+		return nil
+	})
+	if err == nil {
+		log.Info(fmt.Sprintf("Updated %d inference rules of ontology %s", len(rules), ontologyID))
+	}
+	return err
+}
+
 // ListOntologies returns a list of all stored ontologies
 func (ms *MemoryStorage) ListOntologies() []*models.Ontology {
 	ms.mutex.RLock()
@@ -121,30 +233,9 @@ func (ms *MemoryStorage) GetElement(elementName string) (*models.OntologyElement
 	return nil, fmt.Errorf("element not found")
 }
 
-func (ms *MemoryStorage) GetElementRelations(elementName string) ([]*models.Relation, error) {
-	ms.mutex.RLock()
-	defer ms.mutex.RUnlock()
-
-	normalizedName := normalizeElementName(elementName)
-	log.Info(fmt.Sprintf("Searching relations for normalized element name: %s", normalizedName))
-
-	var relations []*models.Relation
-	for _, ontology := range ms.ontologies {
-		for _, relation := range ontology.Relations {
-			if normalizeElementName(relation.Source) == normalizedName ||
-				normalizeElementName(relation.Target) == normalizedName {
-				relations = append(relations, relation)
-				log.Info(fmt.Sprintf("Found relation: %s -> %s -> %s",
-					relation.Source, relation.Type, relation.Target))
-			}
-		}
-	}
-
-	if len(relations) == 0 {
-		return nil, fmt.Errorf("no relations found for element: %s", elementName)
-	}
-
-	return relations, nil
+// OnOntologyLoaded registers a hook run each time an ontology is loaded from files
+func (ms *MemoryStorage) OnOntologyLoaded(hook LoadHook) {
+	ms.loader.AddHook(hook)
 }
 
 // LoadOntologyFromFile loads an ontology from files including metadata
@@ -166,7 +257,30 @@ func loadSourceMetadata(filename string) (*models.SourceMetadata, error) {
 	return &metadata, nil
 }
 
-func normalizeElementName(name string) string {
+// elision restores the apostrophe of a French elided prefix ("l etat" -> "l'etat")
+type elision struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// elisions are compiled once since NormalizeElementName runs for every relation endpoint
+var elisions = func() []elision {
+	prefixes := []string{
+		"l", "d", "j", "m", "t", "s", "c", "n", "qu",
+		"jusqu", "lorsqu", "puisqu", "quoiqu", "quelqu",
+	}
+	compiled := make([]elision, len(prefixes))
+	for i, prefix := range prefixes {
+		compiled[i] = elision{
+			pattern:     regexp.MustCompile(fmt.Sprintf(`\b%s \b`, prefix)),
+			replacement: fmt.Sprintf("%s'", prefix),
+		}
+	}
+	return compiled
+}()
+
+// NormalizeElementName ramène un nom d'élément ou d'extrémité de relation à une forme comparable
+func NormalizeElementName(name string) string {
 	parts := strings.SplitN(name, "_", 2)
 	if len(parts) == 2 && (parts[0] == "est" || parts[0] == "a") {
 		return parts[0] + " " + strings.ReplaceAll(parts[1], "_", " ")
@@ -174,15 +288,8 @@ func normalizeElementName(name string) string {
 
 	name = strings.ReplaceAll(name, "_", " ")
 
-	prefixes := []string{
-		"l", "d", "j", "m", "t", "s", "c", "n", "qu",
-		"jusqu", "lorsqu", "puisqu", "quoiqu", "quelqu",
-	}
-
-	for _, prefix := range prefixes {
-		pattern := fmt.Sprintf(`\b%s \b`, prefix)
-		replacement := fmt.Sprintf("%s'", prefix)
-		name = regexp.MustCompile(pattern).ReplaceAllString(name, replacement)
+	for _, e := range elisions {
+		name = e.pattern.ReplaceAllString(name, e.replacement)
 	}
 
 	name = strings.ReplaceAll(name, "aujourd hui", "aujourd'hui")
@@ -196,7 +303,7 @@ func deduplicateTypes(types string) string {
 	typeMap := make(map[string]string)
 	for _, t := range typeSlice {
 		t = strings.TrimSpace(t)
-		normalizedType := normalizeType(t)
+		normalizedType := NormalizeType(t)
 		if existingType, exists := typeMap[normalizedType]; exists {
 			if len(t) > len(existingType) {
 				typeMap[normalizedType] = t
@@ -215,7 +322,9 @@ func deduplicateTypes(types string) string {
 	return strings.Join(uniqueTypes, "/")
 }
 
-func normalizeType(t string) string {
+// NormalizeType returns the key under which element types are compared, so that
+// "Rôle_Public" and "rôle public" are the same type
+func NormalizeType(t string) string {
 	t = strings.ReplaceAll(t, "_", " ")
 	t = strings.Join(strings.Fields(t), " ")
 	return strings.ToLower(t)
@@ -279,13 +388,13 @@ func (ms *MemoryStorage) GetElementContexts(elementName string) ([]models.JSONCo
 	defer ms.mutex.RUnlock()
 
 	log.Info(fmt.Sprintf("GetElementContext Called for: %s", elementName))
-	normalizedName := normalizeElementName(elementName)
+	normalizedName := NormalizeElementName(elementName)
 	log.Info(fmt.Sprintf("Normalized name: %s", normalizedName))
 
 	for _, ontology := range ms.ontologies {
 		for _, elem := range ontology.Elements {
-			log.Info(fmt.Sprintf("Checking element: %s (normalized: %s)", elem.Name, normalizeElementName(elem.Name)))
-			if normalizeElementName(elem.Name) == normalizedName {
+			log.Info(fmt.Sprintf("Checking element: %s (normalized: %s)", elem.Name, NormalizeElementName(elem.Name)))
+			if NormalizeElementName(elem.Name) == normalizedName {
 				log.Info(fmt.Sprintf("GetElementContext found for %s with %d contexts", elem.Name, len(elem.Contexts)))
 				if len(elem.Contexts) == 0 {
 					log.Warning(fmt.Sprintf("Element %s found but has no contexts", elem.Name))
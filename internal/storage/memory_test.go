@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -99,6 +100,47 @@ func TestDeleteOntology(t *testing.T) {
 	}
 }
 
+func TestOnChange(t *testing.T) {
+	ms := NewMemoryStorage()
+	var changes []string
+	ms.OnChange(func(ontologyID string) {
+		changes = append(changes, ontologyID)
+	})
+
+	ms.AddOntology(&models.Ontology{ID: "test1", Name: "Test Ontology"})
+	ms.UpdateOntology(&models.Ontology{ID: "test1", Name: "Updated Test Ontology"})
+	ms.UpdateOntology(&models.Ontology{ID: "nonexistent"})
+	ms.SetElementAltLabels("test1", "Missing", []string{"Absent"})
+	ms.DeleteOntology("test1")
+
+	expected := []string{"test1", "test1", "test1"}
+	if fmt.Sprint(changes) != fmt.Sprint(expected) {
+		t.Errorf("Expected changes %v, got %v", expected, changes)
+	}
+}
+
+func TestSetElementAltLabels(t *testing.T) {
+	ms := NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{ID: "test1", Elements: []*models.OntologyElement{{Name: "Magistrat"}}})
+	before, _ := ms.GetOntology("test1")
+
+	element, err := ms.SetElementAltLabels("test1", "Magistrat", []string{"Juge"})
+	if err != nil || len(element.AltLabels) != 1 {
+		t.Fatalf("Expected the updated element, got %+v (%v)", element, err)
+	}
+	after, _ := ms.GetOntology("test1")
+	if after == before || len(before.Elements[0].AltLabels) != 0 || after.Elements[0] != element {
+		t.Errorf("Expected a new ontology version holding the updated element")
+	}
+
+	if _, err := ms.SetElementAltLabels("test1", "Missing", []string{"Absent"}); err == nil {
+		t.Errorf("Expected an error for an unknown element")
+	}
+	if current, _ := ms.GetOntology("test1"); current != after {
+		t.Errorf("Expected a failed update to leave the ontology unchanged")
+	}
+}
+
 func TestListOntologies(t *testing.T) {
 	ms := NewMemoryStorage()
 	ontology1 := &models.Ontology{ID: "test1", Name: "Test Ontology 1"}
@@ -233,3 +275,138 @@ func TestLoadOntologyWithMetadata(t *testing.T) {
 		}
 	}
 }
+
+func TestGetFileElements(t *testing.T) {
+	ms := NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID: "test1",
+		Elements: []*models.OntologyElement{
+			{
+				Name:      "Conseil_Etat",
+				Positions: []int{100, 250},
+				Contexts: []models.JSONContext{
+					{Position: 100, Length: 2, FileID: "file1", FilePosition: 10},
+					{Position: 250, Length: 2, FileID: "file2", FilePosition: 40},
+				},
+			},
+			{
+				Name:      "Service_Public",
+				Positions: []int{90},
+				Contexts:  []models.JSONContext{{Position: 90, Length: 2, FileID: "file1", FilePosition: 0}},
+			},
+			{Name: "Agent", Positions: []int{300}},
+		},
+		Source: &models.SourceMetadata{Files: map[string]models.FileInfo{"file1": {ID: "file1"}, "file2": {ID: "file2"}}},
+	})
+
+	elements, err := ms.GetFileElements("file1", -1, -1)
+	if err != nil {
+		t.Fatalf("GetFileElements failed: %v", err)
+	}
+	if len(elements) != 2 || elements[0].ElementName != "Service_Public" || elements[1].ElementName != "Conseil_Etat" {
+		t.Fatalf("Expected elements sorted by position, got %+v", elements)
+	}
+
+	elements, _ = ms.GetFileElements("file1", 5, 20)
+	if len(elements) != 1 || elements[0].ElementName != "Conseil_Etat" || elements[0].FilePositions[0] != 10 {
+		t.Errorf("Expected only Conseil_Etat at position 10, got %+v", elements)
+	}
+
+	if _, err := ms.GetFileElements("unknown", -1, -1); err == nil {
+		t.Error("Expected error for an unknown file, got nil")
+	}
+}
+
+func TestTypeHierarchy(t *testing.T) {
+	ms := NewMemoryStorage()
+	ms.AddOntology(&models.Ontology{
+		ID: "onto1",
+		Elements: []*models.OntologyElement{
+			{Name: "Avocate", Type: "Avocat"},
+			{Name: "Greffier_En_Chef", Type: "Greffier"},
+			{Name: "Conseil_Etat", Type: "Organisation/Juridiction"},
+			{Name: "Neutralite", Type: "Concept"},
+		},
+		Relations: []*models.Relation{
+			{Source: "Avocat", Type: "est_un", Target: "Profession_Juridique"},
+			{Source: "Profession_Juridique", Type: "est_un", Target: "Rôle"},
+			{Source: "Avocate", Type: "est_un", Target: "Personne"},
+		},
+		TypeParents: map[string]string{"Greffier": "Profession juridique"},
+	})
+
+	tree, err := ms.GetTypeTree("onto1")
+	if err != nil {
+		t.Fatalf("GetTypeTree failed: %v", err)
+	}
+	var names []string
+	for _, node := range tree {
+		names = append(names, node.Name)
+	}
+	// Avocate est un élément et non un type : sa relation est_un n'entre pas dans la hiérarchie
+	if strings.Join(names, ",") != "Concept,Juridiction,Organisation,Rôle" {
+		t.Fatalf("Unexpected roots: %v", names)
+	}
+	role := tree[3]
+	if role.Count != 0 || role.Total != 2 || len(role.Children) != 1 || len(role.Children[0].Children) != 2 {
+		t.Errorf("Expected Rôle > Profession_Juridique > {Avocat, Greffier} with 2 elements, got %+v", role)
+	}
+
+	ontology, _ := ms.GetOntology("onto1")
+	expanded := ms.TypeHierarchy(ontology).Expand([]string{"Rôle"})
+	if strings.Join(expanded, ",") != "rôle,avocat,greffier,profession juridique" {
+		t.Errorf("Unexpected expansion: %v", expanded)
+	}
+
+	// La taxonomie déclarée remplace la précédente et invalide la hiérarchie en cache
+	ms.SetTypeParents("onto1", map[string]string{"Concept": "Notion"})
+	if tree, _ = ms.GetTypeTree("onto1"); len(tree) != 5 {
+		t.Errorf("Expected Greffier as a new root and Notion above Concept, got %d roots", len(tree))
+	}
+}
+
+func TestResolveRelationEndpoints(t *testing.T) {
+	elements := []*models.OntologyElement{
+		{Name: "Cour_de_cassation"},
+		{Name: "Etat"},
+		{Name: "Conseil_Etat"},
+		{Name: "Juge"},
+		{Name: "Juges"},
+		{Name: "Magistrat", AltLabels: []string{"Magistrate du siège"}},
+	}
+	relations := []*models.Relation{
+		{Source: "Cour de cassation", Type: "relève_de", Target: "l'Etat"},
+		{Source: "Conseil_Etatt", Type: "conseille", Target: "Gouvernement"},
+		{Source: "Magistrat_du_siege", Type: "est_un", Target: "Jugee"},
+		{Source: "Juge", Type: "siège_à", Target: "la Cour_de_cassation"},
+	}
+
+	unresolved := ResolveRelationEndpoints(elements, relations)
+
+	expected := [][2]string{
+		{"Cour_de_cassation", "Etat"},
+		{"Conseil_Etat", ""},
+		{"Magistrat", ""},
+		{"Juge", "Cour_de_cassation"},
+	}
+	for i, relation := range relations {
+		if relation.SourceID != expected[i][0] || relation.TargetID != expected[i][1] {
+			t.Errorf("Relation %d: expected %v, got %s -> %s", i, expected[i], relation.SourceID, relation.TargetID)
+		}
+	}
+
+	if len(unresolved) != 2 {
+		t.Fatalf("Expected 2 unresolved endpoints, got %d", len(unresolved))
+	}
+	if u := unresolved[0]; u.Relation != 1 || u.Endpoint != EndpointTarget || u.Name != "Gouvernement" || len(u.Candidates) != 0 {
+		t.Errorf("Unexpected unresolved endpoint %+v", u)
+	}
+	// Deux éléments aussi proches l'un que l'autre : la correspondance est ambiguë
+	if u := unresolved[1]; u.Relation != 2 || strings.Join(u.Candidates, ",") != "Juge,Juges" {
+		t.Errorf("Expected Juge and Juges as candidates, got %+v", u)
+	}
+
+	if source, target := RelationEndpoints(relations[1]); source != "Conseil_Etat" || target != "Gouvernement" {
+		t.Errorf("Expected resolved source and raw target, got %s -> %s", source, target)
+	}
+}
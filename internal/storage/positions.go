@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+)
+
+// FileElement is an element occurring in a word range of a source file
+type FileElement struct {
+	OntologyID  string `json:"ontology_id"`
+	ElementName string `json:"element_name"`
+	ElementType string `json:"element_type"`
+	Description string `json:"description"`
+	// FilePositions lists the word positions of the occurrences within the range, in ascending order
+	FilePositions []int `json:"file_positions"`
+}
+
+// filePositions returns the positions of an element inside a file, derived from its
+// contexts and, when they can be mapped to the file, from its global positions
+func filePositions(element *models.OntologyElement, fileID string, singleFile bool) map[int]bool {
+	positions := make(map[int]bool)
+	for _, ctx := range element.Contexts {
+		if ctx.FileID == fileID {
+			positions[ctx.FilePosition] = true
+		}
+	}
+
+	for _, pos := range element.Positions {
+		mapped := false
+		for _, ctx := range element.Contexts {
+			// A context covers the words [Position, Position+Length) of its occurrence
+			if pos >= ctx.Position && pos < ctx.Position+max(ctx.Length, 1) {
+				if ctx.FileID == fileID {
+					positions[ctx.FilePosition+pos-ctx.Position] = true
+				}
+				mapped = true
+				break
+			}
+		}
+		// Without context, global positions are file positions only when the ontology has a single source file
+		if !mapped && singleFile {
+			positions[pos] = true
+		}
+	}
+	return positions
+}
+
+// GetFileElements returns the elements whose positions or contexts fall within
+// [from, to] in the given source file, sorted by their first position in the range.
+// A negative bound is treated as open.
+func (ms *MemoryStorage) GetFileElements(fileID string, from, to int) ([]FileElement, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	known := false
+	elements := []FileElement{}
+	for _, ontology := range ms.ontologies {
+		singleFile := false
+		if ontology.Source != nil {
+			if _, exists := ontology.Source.Files[fileID]; exists {
+				known = true
+				singleFile = len(ontology.Source.Files) == 1
+			}
+		}
+
+		for _, element := range ontology.Elements {
+			var inRange []int
+			for pos := range filePositions(element, fileID, singleFile) {
+				known = true
+				if (from < 0 || pos >= from) && (to < 0 || pos <= to) {
+					inRange = append(inRange, pos)
+				}
+			}
+			if len(inRange) == 0 {
+				continue
+			}
+			sort.Ints(inRange)
+			elements = append(elements, FileElement{
+				OntologyID:    ontology.ID,
+				ElementName:   element.Name,
+				ElementType:   element.Type,
+				Description:   element.Description,
+				FilePositions: inRange,
+			})
+		}
+	}
+
+	if !known {
+		return nil, fmt.Errorf("file %s not found", fileID)
+	}
+
+	sort.Slice(elements, func(i, j int) bool {
+		if elements[i].FilePositions[0] != elements[j].FilePositions[0] {
+			return elements[i].FilePositions[0] < elements[j].FilePositions[0]
+		}
+		return elements[i].ElementName < elements[j].ElementName
+	})
+	log.Info(fmt.Sprintf("Found %d elements in file %s between positions %d and %d", len(elements), fileID, from, to))
+	return elements, nil
+}
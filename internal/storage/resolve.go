@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/agnivade/levenshtein"
+	"github.com/chrlesur/ontology-server/internal/models"
+)
+
+// Endpoint resolution thresholds
+const (
+	// MinEndpointSimilarity is the similarity above which a fuzzy match links an endpoint to an element
+	MinEndpointSimilarity = 0.85
+	// minCandidateSimilarity is the similarity above which an element is suggested for an unresolved endpoint
+	minCandidateSimilarity = 0.6
+	// maxEndpointCandidates bounds the suggestions reported for an unresolved endpoint
+	maxEndpointCandidates = 3
+)
+
+// Endpoint names reported in models.UnresolvedEndpoint
+const (
+	EndpointSource = "source"
+	EndpointTarget = "target"
+)
+
+// leadingArticles are dropped from resolution keys, so that "la Cour_de_cassation" matches "Cour de cassation"
+var leadingArticles = []string{"le ", "la ", "les ", "l'", "un ", "une ", "des ", "du ", "d'"}
+
+// resolutionKey reduces a name to the form compared during endpoint resolution:
+// normalised, lower-cased and stripped of a leading article
+func resolutionKey(name string) string {
+	key := strings.ToLower(NormalizeElementName(name))
+	key = strings.ReplaceAll(key, "’", "'")
+	for _, article := range leadingArticles {
+		if trimmed := strings.TrimPrefix(key, article); trimmed != key && trimmed != "" {
+			key = strings.TrimSpace(trimmed)
+			break
+		}
+	}
+	return key
+}
+
+// endpointCandidate is an element name together with one of its resolution keys
+type endpointCandidate struct {
+	key     string
+	runes   int
+	element string
+}
+
+// endpointResolver links relation endpoints to the elements of an ontology
+type endpointResolver struct {
+	names      map[string]bool
+	byKey      map[string][]string
+	candidates []endpointCandidate
+	resolved   map[string]resolution
+}
+
+// resolution is the outcome of resolving one endpoint name
+type resolution struct {
+	element    string
+	candidates []string
+}
+
+func newEndpointResolver(elements []*models.OntologyElement) *endpointResolver {
+	r := &endpointResolver{
+		names:    make(map[string]bool),
+		byKey:    make(map[string][]string),
+		resolved: make(map[string]resolution),
+	}
+	for _, element := range elements {
+		r.names[element.Name] = true
+		for _, label := range append([]string{element.Name, element.OriginalName}, element.AltLabels...) {
+			key := resolutionKey(label)
+			if key == "" || containsString(r.byKey[key], element.Name) {
+				continue
+			}
+			r.byKey[key] = append(r.byKey[key], element.Name)
+			r.candidates = append(r.candidates, endpointCandidate{key, len([]rune(key)), element.Name})
+		}
+	}
+	return r
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the element an endpoint refers to, trying in turn the exact name, the resolution key
+// and the closest key by edit distance. Ambiguous or weak matches leave the endpoint unresolved,
+// with the closest elements as candidates.
+func (r *endpointResolver) resolve(name string) resolution {
+	if res, exists := r.resolved[name]; exists {
+		return res
+	}
+	res := r.match(name)
+	r.resolved[name] = res
+	return res
+}
+
+func (r *endpointResolver) match(name string) resolution {
+	if r.names[name] {
+		return resolution{element: name}
+	}
+	key := resolutionKey(name)
+	if elements := r.byKey[key]; len(elements) == 1 {
+		return resolution{element: elements[0]}
+	} else if len(elements) > 1 {
+		return resolution{candidates: elements}
+	}
+
+	type scored struct {
+		element    string
+		similarity float64
+	}
+	best := make(map[string]float64)
+	keyRunes := len([]rune(key))
+	for _, candidate := range r.candidates {
+		longest := keyRunes
+		if candidate.runes > longest {
+			longest = candidate.runes
+		}
+		if longest == 0 {
+			continue
+		}
+		// The length difference is a lower bound of the edit distance
+		diff := keyRunes - candidate.runes
+		if diff < 0 {
+			diff = -diff
+		}
+		if 1-float64(diff)/float64(longest) < minCandidateSimilarity {
+			continue
+		}
+		similarity := 1 - float64(levenshtein.ComputeDistance(key, candidate.key))/float64(longest)
+		if similarity >= minCandidateSimilarity && similarity > best[candidate.element] {
+			best[candidate.element] = similarity
+		}
+	}
+
+	var ranked []scored
+	for element, similarity := range best {
+		ranked = append(ranked, scored{element, similarity})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].similarity != ranked[j].similarity {
+			return ranked[i].similarity > ranked[j].similarity
+		}
+		return ranked[i].element < ranked[j].element
+	})
+
+	if len(ranked) > 0 && ranked[0].similarity >= MinEndpointSimilarity &&
+		(len(ranked) == 1 || ranked[1].similarity < ranked[0].similarity) {
+		return resolution{element: ranked[0].element}
+	}
+	var res resolution
+	for i := 0; i < len(ranked) && i < maxEndpointCandidates; i++ {
+		res.candidates = append(res.candidates, ranked[i].element)
+	}
+	return res
+}
+
+// ResolveRelationEndpoints links the source and target of each relation to an element, filling
+// SourceID and TargetID, and returns the endpoints that match no element unambiguously
+func ResolveRelationEndpoints(elements []*models.OntologyElement, relations []*models.Relation) []*models.UnresolvedEndpoint {
+	r := newEndpointResolver(elements)
+	var unresolved []*models.UnresolvedEndpoint
+	for i, relation := range relations {
+		for _, endpoint := range []struct {
+			kind string
+			name string
+			id   *string
+		}{
+			{EndpointSource, relation.Source, &relation.SourceID},
+			{EndpointTarget, relation.Target, &relation.TargetID},
+		} {
+			res := r.resolve(endpoint.name)
+			*endpoint.id = res.element
+			if res.element == "" {
+				unresolved = append(unresolved, &models.UnresolvedEndpoint{
+					Relation:   i,
+					Endpoint:   endpoint.kind,
+					Name:       endpoint.name,
+					Candidates: res.candidates,
+				})
+			}
+		}
+	}
+	return unresolved
+}
+
+// resolveOntologyEndpoints resolves the relation endpoints of an ontology before it is stored,
+// so that every stored ontology reports its unresolved endpoints, however it was created
+func resolveOntologyEndpoints(ontology *models.Ontology) {
+	ontology.UnresolvedEndpoints = ResolveRelationEndpoints(ontology.Elements, ontology.Relations)
+	if len(ontology.UnresolvedEndpoints) > 0 {
+		log.Warning(fmt.Sprintf("%d relation endpoints of ontology %s match no element", len(ontology.UnresolvedEndpoints), ontology.ID))
+	}
+}
+
+// RelationEndpoints returns the elements a relation links, as resolved at load time,
+// falling back to the raw source and target text for unresolved endpoints
+func RelationEndpoints(relation *models.Relation) (source, target string) {
+	source, target = relation.Source, relation.Target
+	if relation.SourceID != "" {
+		source = relation.SourceID
+	}
+	if relation.TargetID != "" {
+		target = relation.TargetID
+	}
+	return source, target
+}
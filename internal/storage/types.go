@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chrlesur/ontology-server/internal/models"
+)
+
+// subClassRelations are the relation types read as "is a subtype of" when they link two types
+var subClassRelations = map[string]bool{
+	"est un":         true,
+	"sous classe de": true,
+	"subclassof":     true,
+	"is a":           true,
+}
+
+// TypeNode is a node of the element type tree of an ontology
+type TypeNode struct {
+	Name string `json:"name"`
+	// Count is the number of elements declaring this type
+	Count int `json:"count"`
+	// Total is the number of elements declaring this type or one of its subtypes
+	Total    int         `json:"total"`
+	Children []*TypeNode `json:"children"`
+}
+
+// TypeHierarchy is the subtype graph of the element types of an ontology.
+// Types are keyed by NormalizeType, so "Rôle_Public" and "rôle public" are the same type.
+type TypeHierarchy struct {
+	// names maps each type to its display name
+	names map[string]string
+	// parents and children link each type to its direct supertypes and subtypes
+	parents  map[string][]string
+	children map[string][]string
+	// counts is the number of elements declaring each type
+	counts map[string]int
+	// members lists the elements declaring each type, to count subtree totals without duplicates
+	members map[string][]*models.OntologyElement
+}
+
+// elementTypes splits a possibly combined element type such as "Concept/Rôle"
+func elementTypes(elementType string) []string {
+	var types []string
+	for _, t := range strings.Split(elementType, "/") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// buildTypeHierarchy derives the type hierarchy of an ontology from the types of its
+// elements, its declared taxonomy and its subclass relations between types
+func buildTypeHierarchy(ontology *models.Ontology) *TypeHierarchy {
+	h := &TypeHierarchy{
+		names:    make(map[string]string),
+		parents:  make(map[string][]string),
+		children: make(map[string][]string),
+		counts:   make(map[string]int),
+		members:  make(map[string][]*models.OntologyElement),
+	}
+	for _, element := range ontology.Elements {
+		for _, t := range elementTypes(element.Type) {
+			key := h.add(t)
+			h.counts[key]++
+			h.members[key] = append(h.members[key], element)
+		}
+	}
+	for child, parent := range ontology.TypeParents {
+		h.link(h.add(child), h.add(parent))
+	}
+
+	// A subclass relation links two types when its source is a known type; its target then
+	// becomes a type too, which may in turn be the source of another subclass relation
+	for changed := true; changed; {
+		changed = false
+		for _, relation := range ontology.Relations {
+			if !subClassRelations[NormalizeType(relation.Type)] {
+				continue
+			}
+			child, parent := NormalizeType(relation.Source), NormalizeType(relation.Target)
+			if _, known := h.names[child]; !known {
+				continue
+			}
+			if _, known := h.names[parent]; !known {
+				h.add(relation.Target)
+				changed = true
+			}
+			h.link(child, parent)
+		}
+	}
+	return h
+}
+
+// add registers a type and returns its key
+func (h *TypeHierarchy) add(name string) string {
+	key := NormalizeType(name)
+	if _, exists := h.names[key]; !exists {
+		h.names[key] = strings.TrimSpace(name)
+	}
+	return key
+}
+
+// link records that child is a direct subtype of parent
+func (h *TypeHierarchy) link(child, parent string) {
+	if child == parent {
+		return
+	}
+	for _, p := range h.parents[child] {
+		if p == parent {
+			return
+		}
+	}
+	h.parents[child] = append(h.parents[child], parent)
+	h.children[parent] = append(h.children[parent], child)
+}
+
+// descendants returns the keys of a type and all its subtypes
+func (h *TypeHierarchy) descendants(key string) map[string]bool {
+	seen := map[string]bool{key: true}
+	queue := []string{key}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range h.children[current] {
+			if !seen[child] {
+				seen[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	return seen
+}
+
+// Expand returns the keys of the given types followed by the keys of all their subtypes.
+// Keys are compared with NormalizeType, so a type may be spelled differently in each element.
+func (h *TypeHierarchy) Expand(types []string) []string {
+	var expanded []string
+	seen := make(map[string]bool)
+	for _, t := range types {
+		if key := NormalizeType(t); !seen[key] {
+			seen[key] = true
+			expanded = append(expanded, key)
+		}
+	}
+	for _, t := range types {
+		var subtypes []string
+		for key := range h.descendants(NormalizeType(t)) {
+			if !seen[key] {
+				seen[key] = true
+				subtypes = append(subtypes, key)
+			}
+		}
+		sort.Strings(subtypes)
+		expanded = append(expanded, subtypes...)
+	}
+	return expanded
+}
+
+// Tree returns the type hierarchy as a forest rooted at the types without supertype.
+// A type with several supertypes appears under each of them.
+func (h *TypeHierarchy) Tree() []*TypeNode {
+	var roots []string
+	for key := range h.names {
+		if len(h.parents[key]) == 0 {
+			roots = append(roots, key)
+		}
+	}
+	// Types only reachable through a cycle have no root: show each cycle from its first type
+	covered := make(map[string]bool)
+	for _, root := range roots {
+		for key := range h.descendants(root) {
+			covered[key] = true
+		}
+	}
+	var uncovered []string
+	for key := range h.names {
+		if !covered[key] {
+			uncovered = append(uncovered, key)
+		}
+	}
+	sort.Strings(uncovered)
+	for _, key := range uncovered {
+		if !covered[key] {
+			roots = append(roots, key)
+			for d := range h.descendants(key) {
+				covered[d] = true
+			}
+		}
+	}
+
+	nodes := make([]*TypeNode, 0, len(roots))
+	for _, root := range roots {
+		nodes = append(nodes, h.node(root, map[string]bool{}))
+	}
+	sortTypeNodes(nodes)
+	return nodes
+}
+
+// node builds the subtree of a type; path guards against cycles in declared hierarchies
+func (h *TypeHierarchy) node(key string, path map[string]bool) *TypeNode {
+	path[key] = true
+	defer delete(path, key)
+
+	elements := make(map[*models.OntologyElement]bool)
+	for d := range h.descendants(key) {
+		for _, element := range h.members[d] {
+			elements[element] = true
+		}
+	}
+	n := &TypeNode{Name: h.names[key], Count: h.counts[key], Total: len(elements), Children: []*TypeNode{}}
+	for _, child := range h.children[key] {
+		if !path[child] {
+			n.Children = append(n.Children, h.node(child, path))
+		}
+	}
+	sortTypeNodes(n.Children)
+	return n
+}
+
+// sortTypeNodes orders sibling types by name
+func sortTypeNodes(nodes []*TypeNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name)
+	})
+}
+
+// typeHierarchies caches the type hierarchy of each ontology version
+type typeHierarchies struct {
+	mutex       sync.Mutex
+	ontologies  map[string]*models.Ontology
+	hierarchies map[string]*TypeHierarchy
+}
+
+// get returns the cached hierarchy of an ontology, rebuilt when the ontology has been replaced
+func (c *typeHierarchies) get(ontology *models.Ontology) *TypeHierarchy {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.ontologies == nil {
+		c.ontologies = make(map[string]*models.Ontology)
+		c.hierarchies = make(map[string]*TypeHierarchy)
+	}
+	if c.ontologies[ontology.ID] != ontology {
+		c.ontologies[ontology.ID] = ontology
+		c.hierarchies[ontology.ID] = buildTypeHierarchy(ontology)
+	}
+	return c.hierarchies[ontology.ID]
+}
+
+// forget drops the cached hierarchy of a modified or deleted ontology
+func (c *typeHierarchies) forget(ontologyID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.ontologies, ontologyID)
+	delete(c.hierarchies, ontologyID)
+}
+
+// TypeHierarchy returns the type hierarchy of an ontology
+func (ms *MemoryStorage) TypeHierarchy(ontology *models.Ontology) *TypeHierarchy {
+	return ms.types.get(ontology)
+}
+
+// GetTypeTree returns the element types of an ontology as a tree with element counts
+func (ms *MemoryStorage) GetTypeTree(ontologyID string) ([]*TypeNode, error) {
+	ontology, err := ms.GetOntology(ontologyID)
+	if err != nil {
+		return nil, err
+	}
+	return ms.TypeHierarchy(ontology).Tree(), nil
+}
+
+// SetTypeParents replaces the declared taxonomy of an ontology, mapping each type to its parent
+func (ms *MemoryStorage) SetTypeParents(ontologyID string, parents map[string]string) error {
+	err := ms.replaceOntology(ontologyID, func(ontology *models.Ontology) error {
+		ontology.TypeParents = parents
+		return nil
+	})
+	if err == nil {
+		log.Info(fmt.Sprintf("Updated the declared taxonomy of ontology %s: %d types", ontologyID, len(parents)))
+	}
+	return err
+}
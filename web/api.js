@@ -33,21 +33,45 @@ export async function loadOntologies() {
     }
 }
 
-// Charger les types d'éléments (à implémenter côté serveur)
+// Charger les types d'éléments à partir des facettes calculées par le serveur
 export async function loadElementTypes() {
-    // TODO: Implémenter l'appel API réel
-    return ['Concept', 'Relation', 'Instance'];
+    const facets = await loadSearchFacets();
+    return (facets.element_types || []).map(facet => facet.value);
+}
+
+// Charger les décomptes par facette (types, ontologies, fichiers, relations)
+export async function loadSearchFacets(query = '') {
+    const url = `${API_BASE_URL}/search/facets?q=${encodeURIComponent(query)}`;
+    const response = await fetch(url);
+    if (!response.ok) {
+        throw new Error('Error loading search facets');
+    }
+    return await response.json();
+}
+
+// Récupérer les complétions pour un préfixe saisi
+export async function getSuggestions(prefix, elementType) {
+    let url = `${API_BASE_URL}/suggest?prefix=${encodeURIComponent(prefix)}`;
+    if (elementType) url += `&type=${encodeURIComponent(elementType)}`;
+
+    const response = await fetch(url);
+    if (!response.ok) {
+        return [];
+    }
+    const data = await response.json();
+    return Array.isArray(data) ? data : [];
 }
 
 // Rechercher dans les ontologies
-export async function searchOntologies(query, fileId, elementType) {
+export async function searchOntologies(query, fileId, elementType, mode) {
     if (!query) {
         throw new Error('Un terme de recherche est requis');
     }
 
-    let url = `${API_BASE_URL}/search?q=${encodeURIComponent(query)}`;
+    let url = `${API_BASE_URL}/search?q=${encodeURIComponent(query)}&in_contexts=true&suggest=true`;
     if (fileId) url += `&file_id=${encodeURIComponent(fileId)}`;
-    if (elementType) url += `&element_type=${encodeURIComponent(elementType)}`;
+    if (elementType) url += `&type=${encodeURIComponent(elementType)}`;
+    if (mode) url += `&mode=${encodeURIComponent(mode)}`;
 
     console.log("Search URL:", url);
 
@@ -61,11 +85,15 @@ export async function searchOntologies(query, fileId, elementType) {
     const data = await response.json();
     console.log('Résultats bruts de la recherche:', data);
 
-    return Array.isArray(data) ? data.map(item => ({
+    // Avec suggest=true, la réponse est un objet portant les résultats et les suggestions
+    const items = Array.isArray(data) ? data : (data.results || []);
+    const results = items.map(item => ({
         ...item,
         sourceFile: item.Source?.source_file || 'Unknown',
         sourceMetadata: item.Source || null
-    })) : [];
+    }));
+    results.didYouMean = data.did_you_mean || [];
+    return results;
 }
 
 // api.js
@@ -139,6 +167,37 @@ export async function getElementRelations(elementName) {
     }
 }
 
+// Récupérer les éléments similaires à un élément
+export async function getSimilarElements(elementName, limit = 10) {
+    try {
+        const url = `${API_BASE_URL}/elements/${encodeURIComponent(elementName)}/similar?limit=${limit}`;
+        const response = await fetch(url);
+        if (!response.ok) {
+            throw new Error(`HTTP error! status: ${response.status}`);
+        }
+        const data = await response.json();
+        return Array.isArray(data) ? data : [];
+    } catch (error) {
+        console.error('Erreur lors de la récupération des éléments similaires:', error);
+        return [];
+    }
+}
+
+// Récupérer le voisinage d'un élément jusqu'à depth sauts
+export async function getNeighbourhood(elementName, depth = 2) {
+    try {
+        const url = `${API_BASE_URL}/elements/${encodeURIComponent(elementName)}/neighbourhood?depth=${depth}`;
+        const response = await fetch(url);
+        if (!response.ok) {
+            throw new Error(`HTTP error! status: ${response.status}`);
+        }
+        return await response.json();
+    } catch (error) {
+        console.error('Erreur lors de la récupération du voisinage:', error);
+        return { nodes: [], edges: [] };
+    }
+}
+
 // Récupérer les métadonnées d'une ontologie
 export async function getOntologyMetadata(ontologyId) {
     try {
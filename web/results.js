@@ -3,12 +3,26 @@
 import { 
     getElementDetails, 
     getElementRelations,
+    getSimilarElements,
+    getNeighbourhood,
     loadOntologies 
 } from './api.js';
 import { createRelationsGraph } from './graph.js';
 import { showErrorMessage } from './main.js';
 import { performSearch } from './search.js'; // Ajout de l'import manquant
 
+// Élément affiché, redessiné lorsque la profondeur du graphique change
+let currentElementName = null;
+
+document.addEventListener('DOMContentLoaded', () => {
+    const depthSelect = document.getElementById('graph-depth');
+    if (depthSelect) {
+        depthSelect.addEventListener('change', () => {
+            if (currentElementName) showElementDetails(currentElementName);
+        });
+    }
+});
+
 export function displayResults(results) {
     console.log("Received results:", results);
     const resultsList = document.getElementById('results-list');
@@ -16,6 +30,23 @@ export function displayResults(results) {
 
     if (!results || results.length === 0) {
         resultsList.innerHTML = '<div class="empty-state">Aucun résultat trouvé</div>';
+        if (results && results.didYouMean && results.didYouMean.length > 0) {
+            const suggestions = document.createElement('div');
+            suggestions.className = 'did-you-mean';
+            suggestions.textContent = 'Vouliez-vous dire : ';
+            results.didYouMean.forEach((alternative, index) => {
+                const link = document.createElement('a');
+                link.href = '#';
+                link.textContent = alternative;
+                link.addEventListener('click', (e) => {
+                    e.preventDefault();
+                    performSearch(alternative);
+                });
+                if (index > 0) suggestions.appendChild(document.createTextNode(', '));
+                suggestions.appendChild(link);
+            });
+            resultsList.appendChild(suggestions);
+        }
         return;
     }
     
@@ -58,6 +89,9 @@ export function displayResults(results) {
             <div class="result-item-content">
                 <h3>${escapeHtml(result.ElementName)}</h3>
                 <p>${escapeHtml(result.Description || '')}</p>
+                ${result.ContextMatches && result.ContextMatches.length > 0
+                    ? `<p class="context-snippet">${renderSnippet(result.ContextMatches[0])}</p>`
+                    : ''}
             </div>
             <div class="result-item-meta">
                 <div class="file-name">${escapeHtml(fileInfo ? fileInfo.source_file : 'Fichier inconnu')} (${fileID || 'ID inconnu'})</div>
@@ -89,6 +123,21 @@ export function displayResults(results) {
     });
 }
 
+// Construire le HTML d'un extrait de contexte avec ses passages mis en évidence
+function renderSnippet(match) {
+    const chars = Array.from(match.snippet || '');
+    let html = '';
+    let cursor = 0;
+    (match.highlights || []).forEach(span => {
+        if (span.start < cursor) return; // Ignorer les chevauchements
+        html += escapeHtml(chars.slice(cursor, span.start).join(''));
+        html += `<mark class="${span.kind}">${escapeHtml(chars.slice(span.start, span.end).join(''))}</mark>`;
+        cursor = span.end;
+    });
+    html += escapeHtml(chars.slice(cursor).join(''));
+    return html;
+}
+
 function escapeHtml(unsafe) {
     if (!unsafe) return '';
     return unsafe
@@ -122,6 +171,7 @@ async function showElementDetails(elementName) {
     const loadingSpinner = document.getElementById('loading-spinner');
     if (loadingSpinner) loadingSpinner.classList.remove('hidden');
 
+    currentElementName = elementName;
     try {
         // Récupérer les détails de l'élément
         const element = await getElementDetails(elementName);
@@ -141,11 +191,20 @@ async function showElementDetails(elementName) {
 
         // Afficher les contextes
         displayElementContexts(element);
+
+        // Afficher les éléments similaires
+        displaySimilarElements(await getSimilarElements(elementName));
         
         // Gérer les relations
         const relations = await getElementRelations(elementName);
         if (relations && relations.length > 0) {
-            createRelationsGraph(element, relations);
+            // Au-delà d'un saut, le graphique montre le voisinage élargi de l'élément
+            const depthSelect = document.getElementById('graph-depth');
+            const depth = depthSelect ? parseInt(depthSelect.value, 10) : 1;
+            const graphRelations = depth > 1
+                ? neighbourhoodRelations(await getNeighbourhood(elementName, depth))
+                : relations;
+            createRelationsGraph(element, graphRelations.length > 0 ? graphRelations : relations);
             displayRelationsList(relations);
         } else {
             document.getElementById('element-relations-graph').innerHTML = 
@@ -204,6 +263,45 @@ function displayElementContexts(element) {
     });
 }
 
+function displaySimilarElements(similar) {
+    const container = document.getElementById('element-similar');
+    if (!container) return;
+
+    container.innerHTML = '<h3>Éléments similaires</h3>';
+    if (similar.length === 0) {
+        container.innerHTML += '<div class="empty-state">Aucun élément similaire</div>';
+        return;
+    }
+
+    const ul = document.createElement('ul');
+    ul.className = 'similar-list';
+    similar.forEach(item => {
+        const li = document.createElement('li');
+        li.innerHTML = `
+            <a href="#">${escapeHtml(item.element_name)}</a>
+            <span class="similar-meta">${escapeHtml(item.element_type || '')} — ${escapeHtml(item.ontology_name || item.ontology_id)} (${item.score.toFixed(2)})</span>
+        `;
+        li.querySelector('a').addEventListener('click', (event) => {
+            event.preventDefault();
+            showElementDetails(item.element_name);
+        });
+        ul.appendChild(li);
+    });
+    container.appendChild(ul);
+}
+
+// Convertit les arêtes d'un voisinage en relations nommées pour le graphique
+function neighbourhoodRelations(subgraph) {
+    const names = new Map();
+    (subgraph.nodes || []).forEach(node => names.set(`${node.ontology_id}/${node.id}`, node.name));
+    return (subgraph.edges || []).map(edge => ({
+        Source: names.get(`${edge.ontology_id}/${edge.source}`) || edge.source,
+        Target: names.get(`${edge.ontology_id}/${edge.target}`) || edge.target,
+        Type: edge.type,
+        Description: edge.description || ''
+    }));
+}
+
 function displayRelationsList(relations) {
     const listContainer = document.getElementById('element-relations-list');
     if (!listContainer) return;
@@ -226,6 +324,12 @@ function displayRelationsList(relations) {
             <span class="relation-type">${escapeHtml(relation.Type)}</span>
             <span class="relation-target">${escapeHtml(relation.Target)}</span>
         `;
+        // Les relations déduites indiquent la règle qui les a produites
+        if (relation.Inference) {
+            li.classList.add('relation-inferred');
+            li.title = `Déduite (${relation.Inference.rule})`;
+            li.innerHTML += `<span class="relation-rule">déduite : ${escapeHtml(relation.Inference.rule)}</span>`;
+        }
         ul.appendChild(li);
     });
 
@@ -1,6 +1,6 @@
 // web/search.js
 
-import { searchOntologies } from './api.js';
+import { searchOntologies, getSuggestions } from './api.js';
 import { displayResults } from './results.js';
 import { showErrorMessage } from './main.js';
 
@@ -33,6 +33,27 @@ export function initSearch() {
 
     // Recherche automatique lors de la saisie
     searchInput.addEventListener('input', debounce(handleSearch, 300));
+
+    // Complétion rapide pendant la saisie
+    searchInput.addEventListener('input', debounce(updateSuggestions, 100));
+}
+
+// Mettre à jour la liste des complétions proposées sous le champ de recherche
+async function updateSuggestions() {
+    const datalist = document.getElementById('search-suggestions');
+    const prefix = searchInput.value.trim();
+    if (!datalist) return;
+
+    datalist.innerHTML = '';
+    if (prefix.length < 2) return;
+
+    const suggestions = await getSuggestions(prefix, elementTypeSelect.value);
+    suggestions.forEach(suggestion => {
+        const option = document.createElement('option');
+        option.value = suggestion.name;
+        option.label = `${suggestion.type} - ${suggestion.ontology_name || suggestion.ontology_id}`;
+        datalist.appendChild(option);
+    });
 }
 
 // Gestion de la recherche
@@ -42,8 +63,10 @@ export async function handleSearch(event) {
     const query = document.getElementById('search-input').value.trim();
     const fileId = document.getElementById('ontology-select').value;
     const elementType = document.getElementById('element-type-select').value;
+    const modeSelect = document.getElementById('search-mode-select');
+    const mode = modeSelect ? modeSelect.value : '';
 
-    console.log("Search parameters:", { query, fileId, elementType });
+    console.log("Search parameters:", { query, fileId, elementType, mode });
 
     if (!query) {
         console.log("Search aborted: query is empty");
@@ -55,7 +78,7 @@ export async function handleSearch(event) {
     if (loadingSpinner) loadingSpinner.classList.remove('hidden');
 
     try {
-        const results = await searchOntologies(query, fileId, elementType);
+        const results = await searchOntologies(query, fileId, elementType, mode);
         console.log("Search results:", results);
         
         displayResults(results);